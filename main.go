@@ -2,30 +2,90 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"io"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
-	"github.com/charmbracelet/x/ansi"
 	"github.com/muesli/termenv"
 
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/dirdiff"
+	"github.com/dlvhdr/diffnav/pkg/filefilter"
+	"github.com/dlvhdr/diffnav/pkg/generated"
+	"github.com/dlvhdr/diffnav/pkg/git"
+	"github.com/dlvhdr/diffnav/pkg/prefs"
+	"github.com/dlvhdr/diffnav/pkg/review"
 	"github.com/dlvhdr/diffnav/pkg/ui"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+	"github.com/dlvhdr/diffnav/pkg/ui/panes/diffviewer"
+	"github.com/dlvhdr/diffnav/pkg/viewed"
 )
 
 func main() {
+	args, paths := splitPathArgs(os.Args[1:])
+	rendererFlag := flag.String("renderer", "", "diff renderer to use: builtin or delta (default: from the config, else delta when installed)")
+	stagedFlag := flag.Bool("staged", false, "show staged changes instead of the working tree")
+	noIndexFlag := flag.Bool("no-index", false, "compare two files or directories on disk without git")
+	reviewOutputFlag := flag.String("review-output", "-", "file to write review comments to on quit, as a GitHub pull request review (\"-\" for stdout)")
+	skipViewedFlag := flag.Bool("skip-viewed", false, "skip files marked as viewed when moving between files")
+	filterTerms := make([]string, 0)
+	flag.Func("include", "only show files matching a glob or a kind of change (is:added, is:deleted, is:modified, is:renamed, is:copied, is:binary, is:generated), may be repeated", func(term string) error {
+		filterTerms = append(filterTerms, term)
+		return nil
+	})
+	flag.Func("exclude", "hide files matching a glob or a kind of change, may be repeated", func(term string) error {
+		filterTerms = append(filterTerms, "!"+term)
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage:\n  git diff | diffnav [flags]\n  diffnav [flags] [<revision>...] [-- <path>...]\n  diffnav [flags] [--no-index] <path> <path>\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	_ = flag.CommandLine.Parse(args)
+	revisions := flag.Args()
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	config.Set(cfg)
+	km, err := keys.New(cfg.Keys)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	keys.Set(km)
+
+	if *rendererFlag == "" {
+		*rendererFlag = cfg.Renderer
+	}
+	renderer, err := diffviewer.ParseRenderer(*rendererFlag)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	filter, err := filefilter.Parse(strings.Join(filterTerms, " "))
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
 	stat, err := os.Stdin.Stat()
 	if err != nil {
 		panic(err)
 	}
 
-	if stat.Mode()&os.ModeNamedPipe == 0 && stat.Size() == 0 {
-		fmt.Println("No diff, exiting")
-		os.Exit(0)
-	}
+	isPiped := stat.Mode()&os.ModeNamedPipe != 0 || stat.Size() > 0
+	useGit := len(revisions) > 0 || len(paths) > 0 || *stagedFlag || !isPiped
+	noIndex := *noIndexFlag || (!*stagedFlag && len(paths) == 0 && bothExist(revisions))
 
 	if os.Getenv("DEBUG") == "true" {
 		var fileErr error
@@ -53,29 +113,139 @@ func main() {
 		}
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	var b strings.Builder
-
-	for {
-		r, _, err := reader.ReadRune()
-		if err != nil && err == io.EOF {
-			break
+	var source ui.Source
+	if noIndex {
+		compared := append(revisions, paths...)
+		if len(compared) != 2 {
+			fmt.Println("Error: --no-index expects exactly two paths")
+			os.Exit(1)
 		}
-		_, err = b.WriteRune(r)
+		files, err := dirdiff.Compare(compared[0], compared[1])
 		if err != nil {
-			fmt.Println("Error getting input:", err)
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		if len(files) == 0 {
+			fmt.Println("No diff, exiting")
+			os.Exit(0)
+		}
+		source = ui.FilesSource(files)
+	} else {
+		var input io.Reader = os.Stdin
+		if useGit {
+			input, err = git.Diff(git.DiffOptions{Revisions: revisions, Staged: *stagedFlag, Paths: paths})
+			if err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+		}
+
+		// wait for the first bytes so an empty diff exits before the UI starts
+		reader := bufio.NewReader(input)
+		if _, err := reader.Peek(1); err == io.EOF {
+			fmt.Println("No diff, exiting")
+			os.Exit(0)
+		} else if err != nil {
+			fmt.Println("Error:", err)
 			os.Exit(1)
 		}
+		source = ui.PatchSource(reader)
 	}
 
-	input := ansi.Strip(b.String())
-	if strings.TrimSpace(input) == "" {
-		fmt.Println("No input provided, exiting")
-		os.Exit(0)
+	root := repoKey()
+	store, err := viewed.Load(root)
+	if err != nil {
+		fmt.Println("Error loading viewed files:", err)
+		os.Exit(1)
+	}
+	rules, err := generated.Load(root)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
 	}
-	p := tea.NewProgram(ui.New(input), tea.WithMouseAllMotion())
+
+	// a view picked in the UI before wins over the config
+	saved, err := prefs.Load()
+	if err != nil {
+		fmt.Println("Error loading preferences:", err)
+		os.Exit(1)
+	}
+	view := cfg.View
+	if slices.Contains(config.ViewModes, saved.View) {
+		view = saved.View
+	}
+
+	rev := review.New()
+	p := tea.NewProgram(ui.New(source, ui.Options{
+		Renderer:   renderer,
+		Viewed:     store,
+		SkipViewed: *skipViewedFlag,
+		Review:     rev,
+		Filter:     filter,
+		Generated:  rules,
+		View:       view,
+	}), tea.WithMouseAllMotion())
 
 	if _, err := p.Run(); err != nil {
 		log.Fatal(err)
 	}
+
+	if len(rev.Comments) > 0 {
+		if err := writeReview(rev, *reviewOutputFlag); err != nil {
+			fmt.Println("Error writing review:", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// writeReview exports the comments to path, or to stdout when it's "-".
+func writeReview(rev *review.Review, path string) error {
+	if path == "-" {
+		return rev.Write(os.Stdout)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := rev.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// splitPathArgs separates the arguments after "--", which are always paths, from
+// the flags and revisions before it.
+func splitPathArgs(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// repoKey is the root of the repository, where .gitattributes are read from
+// and which viewed files are remembered for. It falls back to the current
+// directory outside of a repository.
+func repoKey() string {
+	if dir, err := git.TopLevel(); err == nil {
+		return dir
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// bothExist reports whether args are two paths on disk, in which case they're
+// compared directly rather than treated as revisions.
+func bothExist(args []string) bool {
+	if len(args) != 2 {
+		return false
+	}
+	for _, arg := range args {
+		if _, err := os.Stat(arg); err != nil {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,317 @@
+// Package config loads the user's settings from
+// $XDG_CONFIG_HOME/diffnav/config.yml, falling back to ~/.config.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"gopkg.in/yaml.v3"
+)
+
+// ViewMode picks how a file's diff is laid out.
+type ViewMode string
+
+const (
+	// ViewAuto shows files side by side when the diff is wide enough, except
+	// for added or deleted ones.
+	ViewAuto       ViewMode = "auto"
+	ViewUnified    ViewMode = "unified"
+	ViewSideBySide ViewMode = "side-by-side"
+)
+
+// ViewModes lists the view modes, in the order they're cycled through.
+var ViewModes = []ViewMode{ViewUnified, ViewSideBySide, ViewAuto}
+
+// Next returns the view mode that follows v when cycling.
+func (v ViewMode) Next() ViewMode {
+	i := slices.Index(ViewModes, v)
+	return ViewModes[(i+1)%len(ViewModes)]
+}
+
+type Config struct {
+	// Renderer is the default diff renderer, "builtin" or "delta". When empty
+	// delta is used if it's installed.
+	Renderer string `yaml:"renderer"`
+	// View lays out diffs until another view is picked in the UI, which is
+	// then remembered.
+	View ViewMode `yaml:"view"`
+	// SideBySideWidth is the width from which the auto view shows diffs side
+	// by side.
+	SideBySideWidth int     `yaml:"sideBySideWidth"`
+	Sidebar         Sidebar `yaml:"sidebar"`
+	Theme           Theme   `yaml:"theme"`
+	Icons           Icons   `yaml:"icons"`
+	// Keys remaps key bindings, by context and then by action.
+	Keys Keys `yaml:"keys"`
+}
+
+type Sidebar struct {
+	// Width is the width of the file tree.
+	Width int `yaml:"width"`
+	// SearchWidth is the width of the sidebar while searching files.
+	SearchWidth int `yaml:"searchWidth"`
+}
+
+// Theme holds every color of the UI. Colors are ANSI color numbers or hex codes.
+type Theme struct {
+	Accent                string `yaml:"accent"`
+	Text                  string `yaml:"text"`
+	Muted                 string `yaml:"muted"`
+	Border                string `yaml:"border"`
+	SelectedBackground    string `yaml:"selectedBackground"`
+	Directory             string `yaml:"directory"`
+	Added                 string `yaml:"added"`
+	Deleted               string `yaml:"deleted"`
+	Modified              string `yaml:"modified"`
+	Renamed               string `yaml:"renamed"`
+	Viewed                string `yaml:"viewed"`
+	Comment               string `yaml:"comment"`
+	Selection             string `yaml:"selection"`
+	AddedLineBackground   string `yaml:"addedLineBackground"`
+	DeletedLineBackground string `yaml:"deletedLineBackground"`
+	AddedEmphBackground   string `yaml:"addedEmphBackground"`
+	DeletedEmphBackground string `yaml:"deletedEmphBackground"`
+	HunkHeader            string `yaml:"hunkHeader"`
+	HunkHeaderBackground  string `yaml:"hunkHeaderBackground"`
+	SearchMatch           string `yaml:"searchMatch"`
+	SearchMatchBackground string `yaml:"searchMatchBackground"`
+	// Syntax is the chroma style used to highlight code with the builtin renderer.
+	Syntax string `yaml:"syntax"`
+}
+
+type Icons struct {
+	File           string `yaml:"file"`
+	DirExpanded    string `yaml:"dirExpanded"`
+	DirCollapsed   string `yaml:"dirCollapsed"`
+	New            string `yaml:"new"`
+	Deleted        string `yaml:"deleted"`
+	Modified       string `yaml:"modified"`
+	Renamed        string `yaml:"renamed"`
+	Copied         string `yaml:"copied"`
+	Binary         string `yaml:"binary"`
+	Submodule      string `yaml:"submodule"`
+	ModeChange     string `yaml:"modeChange"`
+	Viewed         string `yaml:"viewed"`
+	Comment        string `yaml:"comment"`
+	Generated      string `yaml:"generated"`
+	WhitespaceOnly string `yaml:"whitespaceOnly"`
+}
+
+// Keys maps contexts to the keys of their actions, e.g. main.nextHunk.
+type Keys map[string]map[string]KeyList
+
+// KeyList holds the keys bound to an action, in the config file it's either
+// a single key or a list of keys. A key is a sequence of key presses separated
+// by spaces, like "g g".
+type KeyList []string
+
+func (k *KeyList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*k = KeyList{value.Value}
+		return nil
+	}
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return err
+	}
+	*k = keys
+	return nil
+}
+
+func Default() Config {
+	return Config{
+		View:            ViewAuto,
+		SideBySideWidth: 120,
+		Sidebar: Sidebar{
+			Width:       26,
+			SearchWidth: 50,
+		},
+		Theme: Theme{
+			Accent:                "6",
+			Text:                  "254",
+			Muted:                 "8",
+			Border:                "8",
+			SelectedBackground:    "#1b1b33",
+			Directory:             "4",
+			Added:                 "2",
+			Deleted:               "1",
+			Modified:              "3",
+			Renamed:               "5",
+			Viewed:                "2",
+			Comment:               "3",
+			Selection:             "4",
+			AddedLineBackground:   "#0e250e",
+			DeletedLineBackground: "#402a26",
+			AddedEmphBackground:   "#1d5a1d",
+			DeletedEmphBackground: "#7a3329",
+			HunkHeader:            "#868E99",
+			HunkHeaderBackground:  "#10233A",
+			SearchMatch:           "0",
+			SearchMatchBackground: "3",
+			Syntax:                "tokyonight-night",
+		},
+		Icons: Icons{
+			File:           "\uf4a5",
+			DirExpanded:    "\ue5ff",
+			DirCollapsed:   "\ue5fe",
+			New:            "\uf457",
+			Deleted:        "\ueadf",
+			Modified:       "\uf459",
+			Renamed:        "\uf45a",
+			Copied:         "\uf0c5",
+			Binary:         "\uf471",
+			Submodule:      "\uf414",
+			ModeChange:     "\uf120",
+			Viewed:         "\uf00c",
+			Comment:        "\uf27b",
+			Generated:      "\uf013",
+			WhitespaceOnly: "\uf1dd",
+		},
+	}
+}
+
+var current = Default()
+
+// Get returns the configuration in use.
+func Get() Config {
+	return current
+}
+
+// Set replaces the configuration in use, it's meant to be called once on startup.
+func Set(c Config) {
+	current = c
+}
+
+// Path returns where the config file is looked up.
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "diffnav", "config.yml"), nil
+}
+
+// Load reads the config file on top of the defaults. A missing file isn't an
+// error, an invalid one returns a ValidationError listing every problem.
+func Load() (Config, error) {
+	path, err := Path()
+	if err != nil {
+		return Config{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	} else if err != nil {
+		return Config{}, err
+	}
+	return Parse(path, data)
+}
+
+// Parse decodes a config file, path is only used in errors.
+func Parse(path string, data []byte) (Config, error) {
+	c := Default()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if problems := c.validate(); len(problems) > 0 {
+		return Config{}, &ValidationError{Path: path, Problems: problems}
+	}
+	return c, nil
+}
+
+// ValidationError lists the invalid settings of a config file.
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config %s:\n  - %s", e.Path, strings.Join(e.Problems, "\n  - "))
+}
+
+const minSidebarWidth = 10
+
+func (c Config) validate() []string {
+	problems := make([]string, 0)
+	switch c.Renderer {
+	case "", "builtin", "delta":
+	default:
+		problems = append(problems, fmt.Sprintf("renderer: unknown renderer %q, expected builtin or delta", c.Renderer))
+	}
+	if !slices.Contains(ViewModes, c.View) {
+		problems = append(problems, fmt.Sprintf("view: unknown view %q, expected auto, unified or side-by-side", c.View))
+	}
+	if c.SideBySideWidth < 0 {
+		problems = append(problems, "sideBySideWidth: must not be negative")
+	}
+
+	if c.Sidebar.Width < minSidebarWidth {
+		problems = append(problems, fmt.Sprintf("sidebar.width: must be at least %d", minSidebarWidth))
+	}
+	if c.Sidebar.SearchWidth < c.Sidebar.Width {
+		problems = append(problems, "sidebar.searchWidth: must be at least sidebar.width")
+	}
+
+	colors := []struct {
+		name  string
+		value string
+	}{
+		{"accent", c.Theme.Accent},
+		{"text", c.Theme.Text},
+		{"muted", c.Theme.Muted},
+		{"border", c.Theme.Border},
+		{"selectedBackground", c.Theme.SelectedBackground},
+		{"directory", c.Theme.Directory},
+		{"added", c.Theme.Added},
+		{"deleted", c.Theme.Deleted},
+		{"modified", c.Theme.Modified},
+		{"renamed", c.Theme.Renamed},
+		{"viewed", c.Theme.Viewed},
+		{"comment", c.Theme.Comment},
+		{"selection", c.Theme.Selection},
+		{"addedLineBackground", c.Theme.AddedLineBackground},
+		{"deletedLineBackground", c.Theme.DeletedLineBackground},
+		{"addedEmphBackground", c.Theme.AddedEmphBackground},
+		{"deletedEmphBackground", c.Theme.DeletedEmphBackground},
+		{"hunkHeader", c.Theme.HunkHeader},
+		{"hunkHeaderBackground", c.Theme.HunkHeaderBackground},
+		{"searchMatch", c.Theme.SearchMatch},
+		{"searchMatchBackground", c.Theme.SearchMatchBackground},
+	}
+	for _, color := range colors {
+		if !validColor(color.value) {
+			problems = append(problems, fmt.Sprintf("theme.%s: invalid color %q, expected an ANSI color number or a hex code", color.name, color.value))
+		}
+	}
+	if _, ok := styles.Registry[c.Theme.Syntax]; !ok {
+		problems = append(problems, fmt.Sprintf("theme.syntax: unknown chroma style %q", c.Theme.Syntax))
+	}
+	return problems
+}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validColor(s string) bool {
+	if hexColor.MatchString(s) {
+		return true
+	}
+	n, err := strconv.Atoi(s)
+	return err == nil && n >= 0 && n <= 255
+}
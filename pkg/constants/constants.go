@@ -1,6 +0,0 @@
-package constants
-
-const (
-	SearchingFileTreeWidth = 50
-	OpenFileTreeWidth      = 26
-)
@@ -0,0 +1,154 @@
+// Package diffsearch finds text within the lines of diffs.
+package diffsearch
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+
+	"github.com/dlvhdr/diffnav/pkg/worddiff"
+)
+
+// Filter restricts the lines searched by their change.
+type Filter int
+
+const (
+	FilterAll Filter = iota
+	FilterAdded
+	FilterDeleted
+)
+
+func (f Filter) String() string {
+	switch f {
+	case FilterAdded:
+		return "added lines"
+	case FilterDeleted:
+		return "removed lines"
+	default:
+		return "all lines"
+	}
+}
+
+// Next cycles through the filters.
+func (f Filter) Next() Filter {
+	return (f + 1) % 3
+}
+
+func (f Filter) accepts(op gitdiff.LineOp) bool {
+	switch f {
+	case FilterAdded:
+		return op == gitdiff.OpAdd
+	case FilterDeleted:
+		return op == gitdiff.OpDelete
+	default:
+		return true
+	}
+}
+
+// Query is the text to look for. It's case sensitive only when it has upper
+// case letters.
+type Query struct {
+	Text   string
+	Filter Filter
+}
+
+func (q Query) IsZero() bool {
+	return q.Text == ""
+}
+
+// Find returns the byte ranges of the query in a line, none when the line is
+// filtered out.
+func (q Query) Find(op gitdiff.LineOp, line string) []worddiff.Range {
+	if q.Text == "" || !q.Filter.accepts(op) {
+		return nil
+	}
+	needle := q.Text
+	ranges := make([]worddiff.Range, 0)
+	if strings.ToLower(needle) == needle {
+		// the pattern matches the original line, lowering it first could
+		// change the length of some runes
+		for _, loc := range foldPattern(needle).FindAllStringIndex(line, -1) {
+			ranges = append(ranges, worddiff.Range{Start: loc[0], End: loc[1]})
+		}
+		return ranges
+	}
+	for start := 0; ; {
+		i := strings.Index(line[start:], needle)
+		if i == -1 {
+			return ranges
+		}
+		start += i
+		ranges = append(ranges, worddiff.Range{Start: start, End: start + len(needle)})
+		start += len(needle)
+	}
+}
+
+// foldPatterns caches the case insensitive patterns of the queries, as every
+// line of the diff is searched with the same one.
+var foldPatterns sync.Map
+
+func foldPattern(needle string) *regexp.Regexp {
+	if re, ok := foldPatterns.Load(needle); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(needle))
+	foldPatterns.Store(needle, re)
+	return re
+}
+
+// Match is a line of a diff that contains the query.
+type Match struct {
+	File *gitdiff.File
+	Op   gitdiff.LineOp
+	// OldLine and NewLine are the line's numbers in the old and new versions
+	// of the file, zero on the side it's missing from.
+	OldLine int64
+	NewLine int64
+	// Text is the line without its end of line, Ranges are the matches in it.
+	Text   string
+	Ranges []worddiff.Range
+}
+
+// Line returns the line number to show for the match, the new one unless the
+// line was removed.
+func (m Match) Line() int64 {
+	if m.NewLine != 0 {
+		return m.NewLine
+	}
+	return m.OldLine
+}
+
+// Search finds the lines of files that contain the query, in order.
+func Search(files []*gitdiff.File, q Query) []Match {
+	matches := make([]Match, 0)
+	if q.IsZero() {
+		return matches
+	}
+	for _, file := range files {
+		for _, frag := range file.TextFragments {
+			oldNum, newNum := frag.OldPosition, frag.NewPosition
+			for _, line := range frag.Lines {
+				m := Match{File: file, Op: line.Op}
+				switch line.Op {
+				case gitdiff.OpContext:
+					m.OldLine, m.NewLine = oldNum, newNum
+					oldNum++
+					newNum++
+				case gitdiff.OpDelete:
+					m.OldLine = oldNum
+					oldNum++
+				case gitdiff.OpAdd:
+					m.NewLine = newNum
+					newNum++
+				}
+				m.Text = strings.TrimRight(line.Line, "\r\n")
+				if m.Ranges = q.Find(line.Op, m.Text); len(m.Ranges) > 0 {
+					matches = append(matches, m)
+				}
+			}
+		}
+	}
+	return matches
+}
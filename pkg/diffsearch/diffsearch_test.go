@@ -0,0 +1,155 @@
+package diffsearch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+
+	"github.com/dlvhdr/diffnav/pkg/worddiff"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		op    gitdiff.LineOp
+		line  string
+		want  []worddiff.Range
+	}{
+		{
+			name:  "empty query",
+			query: Query{},
+			line:  "anything",
+			want:  nil,
+		},
+		{
+			name:  "every occurrence",
+			query: Query{Text: "ab"},
+			line:  "ab ab abab",
+			want:  []worddiff.Range{{Start: 0, End: 2}, {Start: 3, End: 5}, {Start: 6, End: 8}, {Start: 8, End: 10}},
+		},
+		{
+			name:  "lower case query ignores case",
+			query: Query{Text: "foo"},
+			line:  "Foo FOO foo",
+			want:  []worddiff.Range{{Start: 0, End: 3}, {Start: 4, End: 7}, {Start: 8, End: 11}},
+		},
+		{
+			name:  "upper case query is case sensitive",
+			query: Query{Text: "Foo"},
+			line:  "foo Foo FOO",
+			want:  []worddiff.Range{{Start: 4, End: 7}},
+		},
+		{
+			name:  "ranges stay in the original line when lowering changes its length",
+			query: Query{Text: "x"},
+			line:  "İx X",
+			want:  []worddiff.Range{{Start: 2, End: 3}, {Start: 4, End: 5}},
+		},
+		{
+			name:  "special characters are literal",
+			query: Query{Text: "a.b("},
+			line:  "axb( a.b(",
+			want:  []worddiff.Range{{Start: 5, End: 9}},
+		},
+		{
+			name:  "no match",
+			query: Query{Text: "zz"},
+			line:  "abc",
+			want:  []worddiff.Range{},
+		},
+		{
+			name:  "added filter skips removed lines",
+			query: Query{Text: "a", Filter: FilterAdded},
+			op:    gitdiff.OpDelete,
+			line:  "a",
+			want:  nil,
+		},
+		{
+			name:  "removed filter skips context lines",
+			query: Query{Text: "a", Filter: FilterDeleted},
+			op:    gitdiff.OpContext,
+			line:  "a",
+			want:  nil,
+		},
+		{
+			name:  "removed filter keeps removed lines",
+			query: Query{Text: "a", Filter: FilterDeleted},
+			op:    gitdiff.OpDelete,
+			line:  "a",
+			want:  []worddiff.Range{{Start: 0, End: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.query.Find(tt.op, tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %v, want %v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+const patch = `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -10,2 +10,2 @@
+ keep
+-old value
++new value
+`
+
+func TestSearch(t *testing.T) {
+	files, _, err := gitdiff.Parse(strings.NewReader(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type line struct {
+		op       gitdiff.LineOp
+		old, new int64
+	}
+	tests := []struct {
+		name  string
+		query Query
+		want  []line
+	}{
+		{
+			name:  "empty query",
+			query: Query{},
+			want:  []line{},
+		},
+		{
+			name:  "all lines",
+			query: Query{Text: "e"},
+			want:  []line{{gitdiff.OpContext, 10, 10}, {gitdiff.OpDelete, 11, 0}, {gitdiff.OpAdd, 0, 11}},
+		},
+		{
+			name:  "added lines",
+			query: Query{Text: "value", Filter: FilterAdded},
+			want:  []line{{gitdiff.OpAdd, 0, 11}},
+		},
+		{
+			name:  "removed lines",
+			query: Query{Text: "value", Filter: FilterDeleted},
+			want:  []line{{gitdiff.OpDelete, 11, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := Search(files, tt.query)
+			got := make([]line, len(matches))
+			for i, m := range matches {
+				if m.File != files[0] {
+					t.Errorf("match %d is in %v, want %v", i, m.File, files[0])
+				}
+				got[i] = line{m.Op, m.OldLine, m.NewLine}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package dirdiff compares files and directory trees on disk without git.
+package dirdiff
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+
+	"github.com/dlvhdr/diffnav/pkg/textdiff"
+)
+
+const (
+	contextLines = 3
+	// binarySniffLen is how much of a file git inspects to decide it's binary.
+	binarySniffLen = 8000
+
+	modeRegular    os.FileMode = 0o100644
+	modeExecutable os.FileMode = 0o100755
+)
+
+// Compare diffs the file or directory at oldPath against the one at newPath,
+// the way `git diff --no-index` does. Identical files are left out.
+func Compare(oldPath, newPath string) ([]*gitdiff.File, error) {
+	oldInfo, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := os.Stat(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if oldInfo.IsDir() != newInfo.IsDir() {
+		return nil, fmt.Errorf("cannot compare %s with %s: both must be files or directories", oldPath, newPath)
+	}
+
+	if !oldInfo.IsDir() {
+		file, err := compareFiles(oldPath, newPath, oldPath, newPath)
+		if err != nil || file == nil {
+			return nil, err
+		}
+		return []*gitdiff.File{file}, nil
+	}
+
+	oldFiles, err := listFiles(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := listFiles(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFiles)+len(newFiles))
+	for name := range oldFiles {
+		names = append(names, name)
+	}
+	for name := range newFiles {
+		if !oldFiles[name] {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	files := make([]*gitdiff.File, 0)
+	for _, name := range names {
+		oldName, newName := "", ""
+		if oldFiles[name] {
+			oldName = name
+		}
+		if newFiles[name] {
+			newName = name
+		}
+		file, err := compareFiles(joinIfSet(oldPath, oldName), joinIfSet(newPath, newName), oldName, newName)
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// compareFiles diffs two files on disk, an empty path stands for a missing file.
+// It returns nil when both files are identical.
+func compareFiles(oldPath, newPath, oldName, newName string) (*gitdiff.File, error) {
+	oldContent, oldMode, err := readFile(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newContent, newMode, err := readFile(newPath)
+	if err != nil {
+		return nil, err
+	}
+	if oldPath != "" && newPath != "" && oldMode == newMode && bytes.Equal(oldContent, newContent) {
+		return nil, nil
+	}
+
+	file := &gitdiff.File{
+		OldName:  oldName,
+		NewName:  newName,
+		IsNew:    oldPath == "",
+		IsDelete: newPath == "",
+	}
+	if file.IsNew || file.IsDelete || oldMode != newMode {
+		file.OldMode, file.NewMode = oldMode, newMode
+	}
+
+	if isBinary(oldContent) || isBinary(newContent) {
+		// literal fragments, like git diff --binary without deltas
+		file.IsBinary = true
+		file.BinaryFragment = &gitdiff.BinaryFragment{Method: gitdiff.BinaryPatchLiteral, Size: int64(len(newContent)), Data: newContent}
+		file.ReverseBinaryFragment = &gitdiff.BinaryFragment{Method: gitdiff.BinaryPatchLiteral, Size: int64(len(oldContent)), Data: oldContent}
+		return file, nil
+	}
+	file.TextFragments = textdiff.Fragments(string(oldContent), string(newContent), contextLines)
+	return file, nil
+}
+
+func readFile(path string) ([]byte, os.FileMode, error) {
+	if path == "" {
+		return nil, 0, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	mode := modeRegular
+	if info.Mode()&0o111 != 0 {
+		mode = modeExecutable
+	}
+	return content, mode, nil
+}
+
+// listFiles returns the slash separated paths of all regular files under root.
+func listFiles(root string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
+func joinIfSet(root, name string) string {
+	if name == "" {
+		return ""
+	}
+	return filepath.Join(root, filepath.FromSlash(name))
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) != -1
+}
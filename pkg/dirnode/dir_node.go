@@ -0,0 +1,92 @@
+package dirnode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/tree"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+// DirNode is a directory in the file tree. Directories with a single
+// subdirectory are merged into one node, so Name may span several path segments.
+type DirNode struct {
+	Name      string
+	Path      string
+	Depth     int
+	Collapsed bool
+	Items     []tree.Node
+}
+
+func (d *DirNode) Value() string {
+	cfg := config.Get()
+	icon := cfg.Icons.DirExpanded + " "
+	if d.Collapsed {
+		icon = cfg.Icons.DirCollapsed + " "
+	}
+	maxWidth := cfg.Sidebar.Width - d.Depth*2
+	if !d.Collapsed {
+		return utils.TruncateString(icon+d.Name, maxWidth)
+	}
+
+	added, deleted := d.LineStats()
+	stats := lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Muted)).Render(fmt.Sprintf(" %d ", len(d.Files()))),
+		lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Added)).Render(fmt.Sprintf("+%d", added)),
+		lipgloss.NewStyle().Foreground(lipgloss.Color(cfg.Theme.Deleted)).Render(fmt.Sprintf(" -%d", deleted)),
+	)
+	name := utils.TruncateString(icon+d.Name, maxWidth-lipgloss.Width(stats))
+	spacerWidth := maxWidth - lipgloss.Width(name) - lipgloss.Width(stats)
+	spacer := ""
+	if spacerWidth > 0 {
+		spacer = strings.Repeat(" ", spacerWidth)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, name, spacer, stats)
+}
+
+func (d *DirNode) String() string {
+	return d.Value()
+}
+
+func (d *DirNode) Children() tree.Children {
+	if d.Collapsed {
+		return tree.NodeChildren(nil)
+	}
+	return tree.NodeChildren(d.Items)
+}
+
+func (d *DirNode) Hidden() bool {
+	return false
+}
+
+// Files returns every file under the directory, in display order.
+func (d *DirNode) Files() []*gitdiff.File {
+	files := make([]*gitdiff.File, 0)
+	for _, item := range d.Items {
+		switch item := item.(type) {
+		case *DirNode:
+			files = append(files, item.Files()...)
+		case filenode.FileNode:
+			files = append(files, item.File)
+		}
+	}
+	return files
+}
+
+// LineStats sums the added and deleted lines of every file under the directory.
+func (d *DirNode) LineStats() (int64, int64) {
+	var added, deleted int64
+	for _, file := range d.Files() {
+		for _, frag := range file.TextFragments {
+			added += frag.LinesAdded
+			deleted += frag.LinesDeleted
+		}
+	}
+	return added, deleted
+}
@@ -0,0 +1,169 @@
+// Package filefilter narrows the files of a diff by their kind of change and
+// by glob patterns.
+package filefilter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+)
+
+// Kind is a kind of change made to a file. A file may be of several kinds,
+// e.g. a binary file that was added.
+type Kind string
+
+const (
+	KindAdded    Kind = "added"
+	KindDeleted  Kind = "deleted"
+	KindModified Kind = "modified"
+	KindRenamed  Kind = "renamed"
+	KindCopied   Kind = "copied"
+	KindBinary   Kind = "binary"
+	// KindGenerated is for the files collapsed by default, e.g. those marked
+	// as generated in .gitattributes.
+	KindGenerated Kind = "generated"
+)
+
+// Kinds lists every kind of change, in the order they're documented.
+var Kinds = []Kind{KindAdded, KindDeleted, KindModified, KindRenamed, KindCopied, KindBinary, KindGenerated}
+
+// kindPrefix marks a term as a kind of change rather than a glob.
+const kindPrefix = "is:"
+
+func (k Kind) matches(file *gitdiff.File, isGenerated func(*gitdiff.File) bool) bool {
+	switch k {
+	case KindGenerated:
+		return isGenerated != nil && isGenerated(file)
+	case KindAdded:
+		return file.IsNew
+	case KindDeleted:
+		return file.IsDelete
+	case KindRenamed:
+		return file.IsRename
+	case KindCopied:
+		return file.IsCopy
+	case KindBinary:
+		return file.IsBinary
+	default:
+		return !file.IsNew && !file.IsDelete && !file.IsRename && !file.IsCopy
+	}
+}
+
+// Filter keeps the files matching its terms. A term is a glob or a kind of
+// change written as "is:<kind>", and is negated by a leading "!". A file is
+// kept when it's one of the included kinds, matches one of the included globs,
+// and matches none of the negated terms. A filter without terms keeps every file.
+type Filter struct {
+	text         string
+	kinds        []Kind
+	include      []Glob
+	excludeKinds []Kind
+	exclude      []Glob
+}
+
+// Parse reads a filter from whitespace separated terms, e.g.
+// "is:added is:modified *.go !**/*_test.go".
+func Parse(text string) (Filter, error) {
+	f := Filter{text: strings.Join(strings.Fields(text), " ")}
+	for _, term := range strings.Fields(text) {
+		negated := strings.HasPrefix(term, "!")
+		term = strings.TrimPrefix(term, "!")
+		if term == "" {
+			return Filter{}, errors.New("expected a pattern after !")
+		}
+
+		if name, ok := strings.CutPrefix(term, kindPrefix); ok {
+			kind, err := parseKind(name)
+			if err != nil {
+				return Filter{}, err
+			}
+			if negated {
+				f.excludeKinds = append(f.excludeKinds, kind)
+			} else {
+				f.kinds = append(f.kinds, kind)
+			}
+			continue
+		}
+
+		glob, err := ParseGlob(term)
+		if err != nil {
+			return Filter{}, fmt.Errorf("%q: %w", term, err)
+		}
+		if negated {
+			f.exclude = append(f.exclude, glob)
+		} else {
+			f.include = append(f.include, glob)
+		}
+	}
+	return f, nil
+}
+
+func parseKind(name string) (Kind, error) {
+	for _, kind := range Kinds {
+		if string(kind) == name {
+			return kind, nil
+		}
+	}
+	names := make([]string, len(Kinds))
+	for i, kind := range Kinds {
+		names[i] = string(kind)
+	}
+	return "", fmt.Errorf("%q: unknown kind of change, expected %s", kindPrefix+name, strings.Join(names, ", "))
+}
+
+func (f Filter) String() string {
+	return f.text
+}
+
+func (f Filter) IsZero() bool {
+	return f.text == ""
+}
+
+// Match reports whether file is kept by the filter. isGenerated tells the
+// generated files apart, it may be nil when there are none.
+func (f Filter) Match(file *gitdiff.File, isGenerated func(*gitdiff.File) bool) bool {
+	name := filenode.GetFileName(file)
+	if len(f.kinds) > 0 && !anyKind(f.kinds, file, isGenerated) {
+		return false
+	}
+	if len(f.include) > 0 && !anyGlob(f.include, name) {
+		return false
+	}
+	return !anyKind(f.excludeKinds, file, isGenerated) && !anyGlob(f.exclude, name)
+}
+
+// Apply returns the files kept by the filter, in order.
+func (f Filter) Apply(files []*gitdiff.File, isGenerated func(*gitdiff.File) bool) []*gitdiff.File {
+	if f.IsZero() {
+		return files
+	}
+	kept := make([]*gitdiff.File, 0, len(files))
+	for _, file := range files {
+		if f.Match(file, isGenerated) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+func anyKind(kinds []Kind, file *gitdiff.File, isGenerated func(*gitdiff.File) bool) bool {
+	for _, kind := range kinds {
+		if kind.matches(file, isGenerated) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyGlob(globs []Glob, name string) bool {
+	for _, glob := range globs {
+		if glob.Match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package filefilter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+func TestFilterApply(t *testing.T) {
+	files := []*gitdiff.File{
+		{OldName: "main.go", NewName: "main.go"},
+		{NewName: "pkg/new.go", IsNew: true},
+		{OldName: "pkg/old_test.go", IsDelete: true},
+		{OldName: "a.txt", NewName: "b.txt", IsRename: true},
+		{OldName: "logo.png", NewName: "logo.png", IsBinary: true},
+		{OldName: "go.sum", NewName: "go.sum"},
+	}
+	isGenerated := func(file *gitdiff.File) bool { return file.NewName == "go.sum" }
+	tests := []struct {
+		filter string
+		want   []string
+	}{
+		{filter: "", want: []string{"main.go", "pkg/new.go", "pkg/old_test.go", "b.txt", "logo.png", "go.sum"}},
+		{filter: "*.go", want: []string{"main.go", "pkg/new.go", "pkg/old_test.go"}},
+		{filter: "*.go !**/*_test.go", want: []string{"main.go", "pkg/new.go"}},
+		{filter: "is:added is:deleted", want: []string{"pkg/new.go", "pkg/old_test.go"}},
+		{filter: "is:modified", want: []string{"main.go", "logo.png", "go.sum"}},
+		{filter: "is:renamed", want: []string{"b.txt"}},
+		{filter: "is:binary", want: []string{"logo.png"}},
+		{filter: "!is:generated", want: []string{"main.go", "pkg/new.go", "pkg/old_test.go", "b.txt", "logo.png"}},
+		{filter: "is:modified pkg/", want: []string{}},
+		{filter: "is:added pkg/", want: []string{"pkg/new.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := Parse(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0)
+			for _, file := range filter.Apply(files, isGenerated) {
+				name := file.NewName
+				if name == "" {
+					name = file.OldName
+				}
+				got = append(got, name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, text := range []string{"!", "is:nope", "[a"} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", text)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package filefilter
+
+import (
+	"path"
+	"strings"
+)
+
+// Glob matches file paths the way .gitignore patterns do: a pattern without a
+// slash matches a file or directory name at any depth, one with a slash is
+// relative to the root of the diff, "**" matches any number of directories and
+// a trailing slash only matches directories.
+type Glob struct {
+	pattern  string
+	segments []string
+	dirOnly  bool
+}
+
+// ParseGlob checks the syntax of pattern.
+func ParseGlob(pattern string) (Glob, error) {
+	g := Glob{pattern: pattern}
+	p := pattern
+	if strings.HasSuffix(p, "/") {
+		g.dirOnly = true
+		p = strings.TrimSuffix(p, "/")
+	}
+	if !strings.Contains(p, "/") {
+		p = "**/" + p
+	}
+	p = strings.TrimPrefix(p, "/")
+	g.segments = strings.Split(p, "/")
+	for _, seg := range g.segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return Glob{}, err
+		}
+	}
+	return g, nil
+}
+
+func (g Glob) String() string {
+	return g.pattern
+}
+
+// Match reports whether name, or one of the directories it's in, matches.
+func (g Glob) Match(name string) bool {
+	parts := strings.Split(name, "/")
+	for n := len(parts); n > 0; n-- {
+		if n == len(parts) && g.dirOnly {
+			continue
+		}
+		if matchSegments(g.segments, parts[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchName reports whether name itself matches, leaving out the directories
+// it's in, as .gitattributes patterns do.
+func (g Glob) MatchName(name string) bool {
+	return !g.dirOnly && matchSegments(g.segments, strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], parts[0])
+	return ok && matchSegments(pattern[1:], parts[1:])
+}
@@ -0,0 +1,67 @@
+package filefilter
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.go", name: "main.go", want: true},
+		{pattern: "*.go", name: "pkg/ui/view.go", want: true},
+		{pattern: "*.go", name: "main.gox", want: false},
+		{pattern: "/main.go", name: "main.go", want: true},
+		{pattern: "/main.go", name: "cmd/main.go", want: false},
+		{pattern: "pkg/*.go", name: "pkg/a.go", want: true},
+		{pattern: "pkg/*.go", name: "pkg/ui/a.go", want: false},
+		{pattern: "pkg/**/*.go", name: "pkg/a.go", want: true},
+		{pattern: "pkg/**/*.go", name: "pkg/ui/panes/a.go", want: true},
+		{pattern: "**/*_test.go", name: "a_test.go", want: true},
+		{pattern: "vendor", name: "vendor/x/y.go", want: true},
+		{pattern: "vendor", name: "src/vendor/y.go", want: true},
+		{pattern: "vendor/", name: "vendor/y.go", want: true},
+		{pattern: "vendor/", name: "vendor", want: false},
+		{pattern: "docs/", name: "docs/a/b.md", want: true},
+		{pattern: "?.txt", name: "a.txt", want: true},
+		{pattern: "?.txt", name: "ab.txt", want: false},
+		{pattern: "[ab].txt", name: "b.txt", want: true},
+	}
+	for _, tt := range tests {
+		glob, err := ParseGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParseGlob(%q): %v", tt.pattern, err)
+		}
+		if got := glob.Match(tt.name); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGlobMatchName(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.lock", name: "deps/yarn.lock", want: true},
+		{pattern: "vendor", name: "vendor/x.go", want: false},
+		{pattern: "vendor/**", name: "vendor/x.go", want: true},
+		{pattern: "vendor/", name: "vendor", want: false},
+	}
+	for _, tt := range tests {
+		glob, err := ParseGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("ParseGlob(%q): %v", tt.pattern, err)
+		}
+		if got := glob.MatchName(tt.name); got != tt.want {
+			t.Errorf("%q.MatchName(%q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseGlobError(t *testing.T) {
+	if _, err := ParseGlob("[a"); err == nil {
+		t.Error(`ParseGlob("[a") succeeded, want an error`)
+	}
+}
@@ -1,6 +1,7 @@
 package filenode
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
@@ -8,14 +9,24 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/tree"
 
-	"github.com/dlvhdr/diffnav/pkg/constants"
+	"github.com/dlvhdr/diffnav/pkg/config"
 	"github.com/dlvhdr/diffnav/pkg/utils"
 )
 
+// minOldNameWidth is the narrowest the old name of a renamed file is shown in.
+const minOldNameWidth = 4
+
 type FileNode struct {
-	File    *gitdiff.File
-	Depth   int
-	YOffset int
+	File     *gitdiff.File
+	Depth    int
+	YOffset  int
+	Viewed   bool
+	Comments int
+	// Generated files are collapsed in the diff, see the generated package.
+	Generated bool
+	// WhitespaceOnly files have nothing left to show once whitespace changes
+	// are hidden.
+	WhitespaceOnly bool
 }
 
 func (f FileNode) Path() string {
@@ -23,23 +34,50 @@ func (f FileNode) Path() string {
 }
 
 func (f FileNode) Value() string {
-	icon := " "
+	cfg := config.Get()
+	theme, icons := cfg.Theme, cfg.Icons
+	icon := icons.File + " "
+	if IsSubmodule(f.File) {
+		icon = icons.Submodule + " "
+	} else if f.File.IsBinary {
+		icon = icons.Binary + " "
+	}
 	status := " "
 	if f.File.IsNew {
-		status += lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("")
+		status += lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Added)).Render(icons.New)
 	} else if f.File.IsDelete {
-		status += lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("")
+		status += lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Deleted)).Render(icons.Deleted)
+	} else if f.File.IsRename {
+		status += lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Renamed)).Render(icons.Renamed)
+	} else if f.File.IsCopy {
+		status += lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Renamed)).Render(icons.Copied)
 	} else {
-		status += lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("")
+		status += lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Modified)).Render(icons.Modified)
+	}
+
+	if ModeChanged(f.File) {
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render(" "+icons.ModeChange) + status
+	}
+	if f.Generated {
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render(" "+icons.Generated) + status
+	}
+	if f.WhitespaceOnly {
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render(" "+icons.WhitespaceOnly) + status
+	}
+	if f.Comments > 0 {
+		status = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render(fmt.Sprintf(" %s %d", icons.Comment, f.Comments)) + status
 	}
 
 	depthWidth := f.Depth * 2
 	iconsWidth := lipgloss.Width(icon) + lipgloss.Width(status)
-	nameMaxWidth := constants.OpenFileTreeWidth - depthWidth - iconsWidth
+	nameMaxWidth := cfg.Sidebar.Width - depthWidth - iconsWidth
 	base := filepath.Base(f.Path())
 	name := utils.TruncateString(base, nameMaxWidth)
+	if f.File.IsRename || f.File.IsCopy {
+		name = f.renameLabel(nameMaxWidth)
+	}
 
-	spacerWidth := constants.OpenFileTreeWidth - lipgloss.Width(name) - iconsWidth - depthWidth
+	spacerWidth := cfg.Sidebar.Width - lipgloss.Width(name) - iconsWidth - depthWidth
 	if len(name) < len(base) {
 		spacerWidth = spacerWidth - 1
 	}
@@ -48,9 +86,32 @@ func (f FileNode) Value() string {
 		spacer = strings.Repeat(" ", spacerWidth)
 	}
 
+	if f.Viewed {
+		icon = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Viewed)).Render(icons.Viewed) + " "
+		name = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render(name)
+	} else if f.Generated || f.WhitespaceOnly {
+		name = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Faint(true).Render(name)
+	}
+
 	return lipgloss.JoinHorizontal(lipgloss.Top, icon, name, spacer, status)
 }
 
+// renameLabel is the file's name preceded by the name it was renamed or copied
+// from, within width. The old name is truncated first so that the new one
+// stays visible.
+func (f FileNode) renameLabel(width int) string {
+	base := filepath.Base(f.Path())
+	old := f.File.OldName
+	if filepath.Dir(old) == filepath.Dir(f.File.NewName) {
+		old = filepath.Base(old)
+	}
+	arrow := " → " + base
+	if room := width - lipgloss.Width(arrow); room >= minOldNameWidth {
+		return utils.TruncateString(old, room) + arrow
+	}
+	return utils.TruncateString(base, width)
+}
+
 func (f FileNode) String() string {
 	return f.Value()
 }
@@ -63,6 +124,21 @@ func (f FileNode) Hidden() bool {
 	return false
 }
 
+// modeSubmodule is the mode git gives to submodules, whose content is the
+// commit they point to.
+const modeSubmodule = 0o160000
+
+// IsSubmodule reports whether file is a submodule rather than a file.
+func IsSubmodule(file *gitdiff.File) bool {
+	return file.OldMode == modeSubmodule || file.NewMode == modeSubmodule
+}
+
+// ModeChanged reports whether the mode of a file that's neither added nor
+// deleted changed, e.g. when it was made executable.
+func ModeChanged(file *gitdiff.File) bool {
+	return file.OldMode != 0 && file.NewMode != 0 && file.OldMode != file.NewMode
+}
+
 func GetFileName(file *gitdiff.File) string {
 	if file.NewName != "" {
 		return file.NewName
@@ -0,0 +1,165 @@
+// Package fuzzy ranks file paths against a pattern typed by the user, scoring
+// matches the way fzf does: characters at the start of path segments and
+// words, in the file's basename, or next to each other score higher.
+package fuzzy
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	scoreMatch       = 16
+	scoreGapStart    = -3
+	scoreGapExtend   = -1
+	bonusConsecutive = 8
+	// bonusSegment is for a character that starts a path segment.
+	bonusSegment = 10
+	// bonusWord is for a character that starts a word within a segment, after
+	// a separator or at a camelCase hump.
+	bonusWord     = 8
+	bonusCamel    = 7
+	bonusBasename = 6
+)
+
+// Match is a path that matches the pattern.
+type Match struct {
+	// Index is the position of the path in the searched paths.
+	Index int
+	Score int
+	// Positions are the indices of the matched runes in the path.
+	Positions []int
+}
+
+// Find returns the paths matching pattern, best first. Matching ignores case.
+// An empty pattern matches every path, in order.
+func Find(pattern string, paths []string) []Match {
+	matches := make([]Match, 0)
+	p := make([]rune, 0, len(pattern))
+	for _, r := range pattern {
+		p = append(p, unicode.ToLower(r))
+	}
+	for i, path := range paths {
+		if len(p) == 0 {
+			matches = append(matches, Match{Index: i})
+			continue
+		}
+		if m, ok := match(p, path); ok {
+			m.Index = i
+			matches = append(matches, m)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		if a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return len(paths[a.Index]) - len(paths[b.Index])
+	})
+	return matches
+}
+
+// match finds the best alignment of pattern in path. score[i][j] is the best
+// score of the first i+1 pattern runes with the last one matched at path rune j.
+func match(pattern []rune, path string) (Match, bool) {
+	text := []rune(path)
+	lower := make([]rune, len(text))
+	for j, r := range text {
+		lower[j] = unicode.ToLower(r)
+	}
+	if !isSubsequence(pattern, lower) {
+		return Match{}, false
+	}
+
+	n, m := len(pattern), len(text)
+	bonuses := bonuses(text)
+	const none = -1 << 30
+	score := make([][]int, n)
+	from := make([][]int, n)
+	for i := range pattern {
+		score[i] = make([]int, m)
+		from[i] = make([]int, m)
+		// gap is the best score of the previous pattern rune matched before
+		// j-1, less the penalty of the gap up to j, and gapFrom where it was.
+		gap, gapFrom := none, -1
+		for j := range text {
+			score[i][j] = none
+			if i > 0 && j >= 2 && score[i-1][j-2] != none {
+				if opened := score[i-1][j-2] + scoreGapStart; opened > gap+scoreGapExtend {
+					gap, gapFrom = opened, j-2
+				} else {
+					gap += scoreGapExtend
+				}
+			} else if gap != none {
+				gap += scoreGapExtend
+			}
+			if lower[j] != pattern[i] {
+				continue
+			}
+			s := scoreMatch + bonuses[j]
+			switch {
+			case i == 0:
+				score[i][j], from[i][j] = s, -1
+			case j > 0 && score[i-1][j-1] != none && score[i-1][j-1]+bonusConsecutive >= gap:
+				score[i][j], from[i][j] = s+score[i-1][j-1]+bonusConsecutive, j-1
+			case gap != none:
+				score[i][j], from[i][j] = s+gap, gapFrom
+			}
+		}
+	}
+
+	best, end := none, -1
+	for j, s := range score[n-1] {
+		if s > best {
+			best, end = s, j
+		}
+	}
+	if end == -1 {
+		return Match{}, false
+	}
+	positions := make([]int, n)
+	for i := n - 1; i >= 0; i-- {
+		positions[i] = end
+		end = from[i][end]
+	}
+	return Match{Score: best, Positions: positions}, true
+}
+
+func isSubsequence(pattern, text []rune) bool {
+	i := 0
+	for _, r := range text {
+		if i < len(pattern) && r == pattern[i] {
+			i++
+		}
+	}
+	return i == len(pattern)
+}
+
+// bonuses scores each position of text by where it stands in the path.
+func bonuses(text []rune) []int {
+	basename := 0
+	for j, r := range text {
+		if r == '/' {
+			basename = j + 1
+		}
+	}
+	res := make([]int, len(text))
+	for j, r := range text {
+		var prev rune = '/'
+		if j > 0 {
+			prev = text[j-1]
+		}
+		switch {
+		case prev == '/':
+			res[j] = bonusSegment
+		case strings.ContainsRune("_-. ", prev) && !strings.ContainsRune("_-. ", r):
+			res[j] = bonusWord
+		case unicode.IsLower(prev) && unicode.IsUpper(r):
+			res[j] = bonusCamel
+		}
+		if j >= basename {
+			res[j] += bonusBasename
+		}
+	}
+	return res
+}
@@ -0,0 +1,96 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		paths   []string
+		want    []string
+	}{
+		{
+			name:    "empty pattern keeps the order",
+			pattern: "",
+			paths:   []string{"b.go", "a.go"},
+			want:    []string{"b.go", "a.go"},
+		},
+		{
+			name:    "non matching paths are left out",
+			pattern: "xyz",
+			paths:   []string{"main.go", "x/y/z.go", "pkg/ui.go"},
+			want:    []string{"x/y/z.go"},
+		},
+		{
+			name:    "case insensitive",
+			pattern: "README",
+			paths:   []string{"readme.md"},
+			want:    []string{"readme.md"},
+		},
+		{
+			name:    "basename over directories",
+			pattern: "main",
+			paths:   []string{"main/cmd/run.go", "cmd/main.go"},
+			want:    []string{"cmd/main.go", "main/cmd/run.go"},
+		},
+		{
+			name:    "consecutive over scattered",
+			pattern: "view",
+			paths:   []string{"pkg/vendor/index/ew.go", "pkg/ui/view.go"},
+			want:    []string{"pkg/ui/view.go", "pkg/vendor/index/ew.go"},
+		},
+		{
+			name:    "segment starts",
+			pattern: "pud",
+			paths:   []string{"pkg/upload.go", "pkg/ui/diff.go"},
+			want:    []string{"pkg/ui/diff.go", "pkg/upload.go"},
+		},
+		{
+			name:    "camel case humps",
+			pattern: "mm",
+			paths:   []string{"pkg/summary.go", "pkg/mainModel.go"},
+			want:    []string{"pkg/mainModel.go", "pkg/summary.go"},
+		},
+		{
+			name:    "shorter path on ties",
+			pattern: "a.go",
+			paths:   []string{"x/a.go", "a.go"},
+			want:    []string{"a.go", "x/a.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, m := range Find(tt.pattern, tt.paths) {
+				got = append(got, tt.paths[m.Index])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Find(%q) = %q, want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPositions(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    []int
+	}{
+		{pattern: "abc", path: "abc", want: []int{0, 1, 2}},
+		{pattern: "mg", path: "cmd/main.go", want: []int{4, 9}},
+		{pattern: "fü", path: "pkg/füß.go", want: []int{4, 5}},
+	}
+	for _, tt := range tests {
+		matches := Find(tt.pattern, []string{tt.path})
+		if len(matches) != 1 {
+			t.Fatalf("Find(%q, %q) = %v, want a match", tt.pattern, tt.path, matches)
+		}
+		if !reflect.DeepEqual(matches[0].Positions, tt.want) {
+			t.Errorf("Find(%q, %q) positions = %v, want %v", tt.pattern, tt.path, matches[0].Positions, tt.want)
+		}
+	}
+}
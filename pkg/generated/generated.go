@@ -0,0 +1,267 @@
+// Package generated tells which files of a diff are collapsed by default, the
+// way GitHub collapses generated files: those marked linguist-generated,
+// linguist-vendored or -diff in the .gitattributes of their directory or its
+// parents, and those matching a pattern of .diffnavignore.
+package generated
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/dlvhdr/diffnav/pkg/filefilter"
+)
+
+// Reason is why a file is collapsed, empty when it isn't.
+type Reason string
+
+const (
+	ReasonGenerated Reason = "generated"
+	ReasonVendored  Reason = "vendored"
+	ReasonNoDiff    Reason = "no diff"
+	ReasonIgnored   Reason = "ignored"
+)
+
+// Description explains the reason in a sentence.
+func (r Reason) Description() string {
+	switch r {
+	case ReasonGenerated:
+		return "This file is marked as generated in .gitattributes."
+	case ReasonVendored:
+		return "This file is marked as vendored in .gitattributes."
+	case ReasonNoDiff:
+		return "Diffs of this file are disabled in .gitattributes."
+	case ReasonIgnored:
+		return "This file matches a pattern of .diffnavignore."
+	default:
+		return ""
+	}
+}
+
+// attributes that collapse a file.
+const (
+	attrGenerated = "linguist-generated"
+	attrVendored  = "linguist-vendored"
+	attrDiff      = "diff"
+)
+
+// attrRule sets, unsets or resets the attributes of the files matching glob,
+// a nil value resets the attribute to unspecified.
+type attrRule struct {
+	glob  filefilter.Glob
+	attrs map[string]*bool
+}
+
+type ignoreRule struct {
+	glob    filefilter.Glob
+	negated bool
+}
+
+// Rules are the patterns of .gitattributes and .diffnavignore. The zero value
+// and a nil *Rules collapse nothing.
+type Rules struct {
+	// root is the directory the rules were loaded from, the .gitattributes of
+	// its subdirectories are read as the files in them are asked about.
+	root       string
+	attributes []attrRule
+	ignore     []ignoreRule
+
+	mu sync.Mutex
+	// nested are the attributes of the subdirectories by their path relative
+	// to root, nil when a directory has no .gitattributes.
+	nested map[string][]attrRule
+}
+
+// Load reads the .gitattributes and .diffnavignore files at the root of dir,
+// either may be missing.
+func Load(dir string) (*Rules, error) {
+	rules := &Rules{root: dir, nested: make(map[string][]attrRule)}
+	if err := readFile(filepath.Join(dir, ".gitattributes"), rules.parseAttributes); err != nil {
+		return nil, err
+	}
+	if err := readFile(filepath.Join(dir, ".diffnavignore"), rules.parseIgnore); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// nestedAttributes returns the attributes of the .gitattributes in dir, a
+// subdirectory of the root, reading it the first time.
+func (r *Rules) nestedAttributes(dir string) []attrRule {
+	if r.nested == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attributes, ok := r.nested[dir]; ok {
+		return attributes
+	}
+	nested := &Rules{}
+	if err := readFile(filepath.Join(r.root, filepath.FromSlash(dir), ".gitattributes"), nested.parseAttributes); err != nil {
+		log.Debug("ignoring unreadable .gitattributes", "dir", dir, "err", err)
+	}
+	r.nested[dir] = nested.attributes
+	return nested.attributes
+}
+
+func readFile(path string, parse func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := parse(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// parseAttributes reads the attributes diffnav cares about, the others are
+// left out. Lines that don't parse are skipped, as git does.
+func (r *Rules) parseAttributes(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// negative patterns are forbidden in .gitattributes
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "!") {
+			continue
+		}
+		glob, err := filefilter.ParseGlob(fields[0])
+		if err != nil {
+			continue
+		}
+		rule := attrRule{glob: glob, attrs: make(map[string]*bool)}
+		for _, attr := range fields[1:] {
+			parseAttribute(attr, rule.attrs)
+		}
+		if len(rule.attrs) > 0 {
+			r.attributes = append(r.attributes, rule)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseAttribute(attr string, attrs map[string]*bool) {
+	set, unset := true, false
+	switch {
+	case attr == "binary":
+		// binary is a macro for -diff -merge -text
+		attrs[attrDiff] = &unset
+		return
+	case strings.HasPrefix(attr, "-"):
+		attr = attr[1:]
+		if isCollapsing(attr) {
+			attrs[attr] = &unset
+		}
+		return
+	case strings.HasPrefix(attr, "!"):
+		attr = attr[1:]
+		if isCollapsing(attr) {
+			attrs[attr] = nil
+		}
+		return
+	}
+	name, value, hasValue := strings.Cut(attr, "=")
+	if !isCollapsing(name) {
+		return
+	}
+	switch {
+	case !hasValue:
+		attrs[name] = &set
+	case name == attrDiff:
+		// a diff driver still shows a diff
+		attrs[name] = &set
+	case value == "true":
+		attrs[name] = &set
+	case value == "false":
+		attrs[name] = &unset
+	}
+}
+
+func isCollapsing(attr string) bool {
+	return attr == attrGenerated || attr == attrVendored || attr == attrDiff
+}
+
+// parseIgnore reads patterns in the .gitignore syntax: blank lines and lines
+// starting with # are skipped, a leading ! includes files back. Invalid
+// patterns are skipped, as git does.
+func (r *Rules) parseIgnore(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{negated: strings.HasPrefix(line, "!")}
+		glob, err := filefilter.ParseGlob(strings.TrimPrefix(line, "!"))
+		if err != nil {
+			continue
+		}
+		rule.glob = glob
+		r.ignore = append(r.ignore, rule)
+	}
+	return scanner.Err()
+}
+
+// Reason tells why the file at name is collapsed, the last matching pattern
+// of each file wins and the .gitattributes of deeper directories win over
+// those of their parents.
+func (r *Rules) Reason(name string) Reason {
+	if r == nil {
+		return ""
+	}
+	ignored := false
+	for _, rule := range r.ignore {
+		if rule.glob.Match(name) {
+			ignored = !rule.negated
+		}
+	}
+	if ignored {
+		return ReasonIgnored
+	}
+
+	attrs := make(map[string]*bool)
+	apply(attrs, r.attributes, name)
+	// the patterns of a nested .gitattributes are relative to its directory
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := path.Join(parts[:i]...)
+		apply(attrs, r.nestedAttributes(dir), path.Join(parts[i:]...))
+	}
+	switch {
+	case isSet(attrs[attrGenerated]):
+		return ReasonGenerated
+	case isSet(attrs[attrVendored]):
+		return ReasonVendored
+	case attrs[attrDiff] != nil && !*attrs[attrDiff]:
+		return ReasonNoDiff
+	}
+	return ""
+}
+
+// apply sets the attributes of the rules matching name.
+func apply(attrs map[string]*bool, rules []attrRule, name string) {
+	for _, rule := range rules {
+		if !rule.glob.MatchName(name) {
+			continue
+		}
+		for attr, value := range rule.attrs {
+			attrs[attr] = value
+		}
+	}
+}
+
+func isSet(value *bool) bool {
+	return value != nil && *value
+}
@@ -0,0 +1,118 @@
+package generated
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReason(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".gitattributes"), `# comment
+*.pb.go linguist-generated
+api/*.pb.go -linguist-generated
+third_party/** linguist-vendored
+*.svg -diff
+*.png binary
+docs/*.md diff=markdown
+schema.json linguist-generated=true
+schema.json linguist-generated=false
+keep.json linguist-generated
+keep.json !linguist-generated
+!negated linguist-generated
+vendor linguist-vendored
+`)
+	writeFile(t, filepath.Join(dir, ".diffnavignore"), `# lock files
+*.lock
+!keep.lock
+[invalid
+build/
+`)
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want Reason
+	}{
+		{name: "main.go", want: ""},
+		{name: "pkg/x.pb.go", want: ReasonGenerated},
+		{name: "api/x.pb.go", want: ""},
+		{name: "third_party/lib/a.c", want: ReasonVendored},
+		{name: "icons/a.svg", want: ReasonNoDiff},
+		{name: "logo.png", want: ReasonNoDiff},
+		{name: "docs/a.md", want: ""},
+		{name: "schema.json", want: ""},
+		{name: "keep.json", want: ""},
+		// .gitattributes patterns don't apply to the files of a directory
+		{name: "vendor/a.go", want: ""},
+		{name: "yarn.lock", want: ReasonIgnored},
+		{name: "sub/Cargo.lock", want: ReasonIgnored},
+		{name: "keep.lock", want: ""},
+		{name: "build/out.js", want: ReasonIgnored},
+	}
+	for _, tt := range tests {
+		if got := rules.Reason(tt.name); got != tt.want {
+			t.Errorf("Reason(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReasonNested(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".gitattributes"), "*.gen.go linguist-generated\n")
+	if err := os.MkdirAll(filepath.Join(dir, "web", "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "web", ".gitattributes"), `dist/** linguist-generated
+*.gen.go -linguist-generated
+`)
+	writeFile(t, filepath.Join(dir, "web", "api", ".gitattributes"), "/schema.ts linguist-generated\n")
+	rules, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want Reason
+	}{
+		{name: "x.gen.go", want: ReasonGenerated},
+		{name: "web/dist/app.js", want: ReasonGenerated},
+		// patterns are relative to the directory of their .gitattributes
+		{name: "dist/app.js", want: ""},
+		// deeper .gitattributes win
+		{name: "web/x.gen.go", want: ""},
+		{name: "web/api/schema.ts", want: ReasonGenerated},
+		{name: "web/api/v1/schema.ts", want: ""},
+		{name: "other/api/schema.ts", want: ""},
+	}
+	for _, tt := range tests {
+		if got := rules.Reason(tt.name); got != tt.want {
+			t.Errorf("Reason(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadMissingFiles(t *testing.T) {
+	rules, err := Load(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.Reason("a.go"); got != "" {
+		t.Errorf("Reason() = %q, want none", got)
+	}
+	var none *Rules
+	if got := none.Reason("a.go"); got != "" {
+		t.Errorf("nil Reason() = %q, want none", got)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
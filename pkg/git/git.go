@@ -0,0 +1,109 @@
+// Package git is a thin wrapper around the git CLI.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// DiffOptions describe the changes `git diff` should produce.
+type DiffOptions struct {
+	// Revisions are passed as is, e.g. "main..feature" or "HEAD~3".
+	Revisions []string
+	// Staged compares the index instead of the working tree.
+	Staged bool
+	// Paths limit the diff to the given files and directories.
+	Paths []string
+}
+
+// Diff starts `git diff` and streams its output. Once the output is exhausted,
+// reading returns the command's error if it failed.
+func Diff(opts DiffOptions) (io.Reader, error) {
+	// --binary includes the content of binary files, which tells their size,
+	// and --full-index the full hash of the blobs to read more context from
+	args := []string{"diff", "--no-color", "--no-ext-diff", "--binary", "--full-index"}
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	args = append(args, opts.Revisions...)
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Paths...)
+	}
+	return stream(args...)
+}
+
+// cmdReader reads the output of a running command.
+type cmdReader struct {
+	cmd    *exec.Cmd
+	stdout io.Reader
+	stderr *bytes.Buffer
+	err    error
+}
+
+func (r *cmdReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.stdout.Read(p)
+	if err == io.EOF {
+		r.err = io.EOF
+		if waitErr := r.cmd.Wait(); waitErr != nil {
+			r.err = commandError(r.cmd.Args[1], waitErr, r.stderr)
+		}
+		return n, r.err
+	}
+	return n, err
+}
+
+func stream(args ...string) (io.Reader, error) {
+	cmd := exec.Command("git", args...)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, commandError(args[0], err, stderr)
+	}
+	return &cmdReader{cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
+
+func commandError(subcommand string, err error, stderr *bytes.Buffer) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && stderr.Len() > 0 {
+		msg, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+		return fmt.Errorf("git %s: %s", subcommand, msg)
+	}
+	return fmt.Errorf("git %s: %w", subcommand, err)
+}
+
+// TopLevel returns the root directory of the repository containing the
+// current directory.
+func TopLevel() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", commandError("rev-parse", err, stderr)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Blob returns the content of the blob oid, which may be abbreviated.
+func Blob(oid string) ([]byte, error) {
+	cmd := exec.Command("git", "cat-file", "blob", oid)
+	stderr := new(bytes.Buffer)
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, commandError("cat-file", err, stderr)
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+// Package prefs remembers the choices made in the UI across runs, like how
+// diffs are laid out.
+package prefs
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+const stateFile = "prefs.json"
+
+// Prefs are the remembered choices, a field is empty until its choice is made.
+type Prefs struct {
+	View config.ViewMode `json:"view,omitempty"`
+}
+
+// Load reads the remembered choices. A missing or corrupt state file is not
+// an error.
+func Load() (Prefs, error) {
+	path, err := statePath()
+	if err != nil {
+		return Prefs{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Prefs{}, nil
+	} else if err != nil {
+		return Prefs{}, err
+	}
+	var p Prefs
+	if err := json.Unmarshal(data, &p); err != nil {
+		// the choices are only a cache, start over rather than failing
+		log.Debug("ignoring corrupt preferences", "path", path, "err", err)
+		return Prefs{}, nil
+	}
+	return p, nil
+}
+
+// Update applies change to the remembered choices and saves them.
+func Update(change func(*Prefs)) error {
+	p, err := Load()
+	if err != nil {
+		return err
+	}
+	change(&p)
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(path, data)
+}
+
+func statePath() (string, error) {
+	dir, err := utils.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, stateFile), nil
+}
@@ -0,0 +1,88 @@
+// Package review collects comments left on a diff and exports them as the
+// payload of GitHub's "create a review for a pull request" API.
+package review
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+)
+
+// Side is the side of the diff a comment applies to, LEFT is the old version
+// of the file and RIGHT the new one.
+type Side string
+
+const (
+	SideLeft  Side = "LEFT"
+	SideRight Side = "RIGHT"
+)
+
+// Comment is a comment on a line, or a range of lines when StartLine is set.
+type Comment struct {
+	Path      string `json:"path"`
+	Line      int64  `json:"line"`
+	Side      Side   `json:"side"`
+	StartLine int64  `json:"start_line,omitempty"`
+	StartSide Side   `json:"start_side,omitempty"`
+	Body      string `json:"body"`
+}
+
+// Review holds the comments of a review, it is shared by the UI and main.
+type Review struct {
+	Comments []Comment `json:"comments"`
+}
+
+func New() *Review {
+	return &Review{Comments: []Comment{}}
+}
+
+// Find returns the comment ending on line.
+func (r *Review) Find(path string, side Side, line int64) (Comment, bool) {
+	i := r.index(path, side, line)
+	if i == -1 {
+		return Comment{}, false
+	}
+	return r.Comments[i], true
+}
+
+// Set adds the comment, or replaces the one ending on the same line.
+func (r *Review) Set(c Comment) {
+	if i := r.index(c.Path, c.Side, c.Line); i != -1 {
+		r.Comments[i] = c
+		return
+	}
+	r.Comments = append(r.Comments, c)
+}
+
+// Remove deletes the comment ending on line, if any.
+func (r *Review) Remove(path string, side Side, line int64) {
+	if i := r.index(path, side, line); i != -1 {
+		r.Comments = slices.Delete(r.Comments, i, i+1)
+	}
+}
+
+// ForFile returns the comments of the file.
+func (r *Review) ForFile(path string) []Comment {
+	comments := make([]Comment, 0)
+	for _, c := range r.Comments {
+		if c.Path == path {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+// Write encodes the review as the body of a POST to
+// /repos/{owner}/{repo}/pulls/{pull_number}/reviews. No event is set, so the
+// review is created as pending until it's submitted.
+func (r *Review) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Review) index(path string, side Side, line int64) int {
+	return slices.IndexFunc(r.Comments, func(c Comment) bool {
+		return c.Path == path && c.Side == side && c.Line == line
+	})
+}
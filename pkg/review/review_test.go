@@ -0,0 +1,93 @@
+package review
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestWrite(t *testing.T) {
+	r := New()
+	r.Set(Comment{Path: "main.go", Line: 12, Side: SideRight, Body: "Single line."})
+	r.Set(Comment{Path: "main.go", Line: 20, Side: SideRight, StartLine: 18, StartSide: SideRight, Body: "Several lines."})
+	r.Set(Comment{Path: "pkg/a.go", Line: 7, Side: SideLeft, StartLine: 5, StartSide: SideLeft, Body: "Removed lines,\nwith \"quotes\" & <html>."})
+	r.Set(Comment{Path: "pkg/a.go", Line: 3, Side: SideRight, StartLine: 2, StartSide: SideLeft, Body: "Across sides."})
+
+	var buf bytes.Buffer
+	if err := r.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "review.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("Write() =\n%s\nwant\n%s", buf.Bytes(), want)
+	}
+}
+
+func TestWriteEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "{\n  \"comments\": []\n}\n"; got != want {
+		t.Errorf("Write() = %q, want %q", got, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	r := New()
+	r.Set(Comment{Path: "a.go", Line: 5, Side: SideRight, StartLine: 3, StartSide: SideRight, Body: "range"})
+	r.Set(Comment{Path: "a.go", Line: 5, Side: SideLeft, Body: "old"})
+	r.Set(Comment{Path: "b.go", Line: 5, Side: SideRight, Body: "other file"})
+
+	tests := []struct {
+		name string
+		path string
+		side Side
+		line int64
+		want string
+		ok   bool
+	}{
+		{name: "ending line", path: "a.go", side: SideRight, line: 5, want: "range", ok: true},
+		{name: "other side", path: "a.go", side: SideLeft, line: 5, want: "old", ok: true},
+		{name: "other file", path: "b.go", side: SideRight, line: 5, want: "other file", ok: true},
+		{name: "start of a range", path: "a.go", side: SideRight, line: 3},
+		{name: "no comment", path: "a.go", side: SideRight, line: 9},
+	}
+	for _, tt := range tests {
+		c, ok := r.Find(tt.path, tt.side, tt.line)
+		if ok != tt.ok || c.Body != tt.want {
+			t.Errorf("%s: Find() = %q, %v, want %q, %v", tt.name, c.Body, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSetRemove(t *testing.T) {
+	r := New()
+	r.Set(Comment{Path: "a.go", Line: 5, Side: SideRight, Body: "first"})
+	r.Set(Comment{Path: "a.go", Line: 5, Side: SideRight, StartLine: 4, StartSide: SideRight, Body: "edited"})
+	if len(r.Comments) != 1 || r.Comments[0].Body != "edited" {
+		t.Fatalf("Set() on the same line = %+v, want it replaced", r.Comments)
+	}
+	r.Set(Comment{Path: "b.go", Line: 1, Side: SideRight, Body: "b"})
+	if got := r.ForFile("a.go"); len(got) != 1 || got[0].Body != "edited" {
+		t.Errorf("ForFile() = %+v", got)
+	}
+	r.Remove("a.go", SideRight, 5)
+	r.Remove("a.go", SideRight, 5)
+	if _, ok := r.Find("a.go", SideRight, 5); ok || len(r.Comments) != 1 {
+		t.Errorf("Remove() left %+v", r.Comments)
+	}
+}
@@ -0,0 +1,117 @@
+package textdiff
+
+import (
+	"fmt"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// Context tells how many of the unchanged lines a patch leaves out around its
+// fragments to show. Gap i is above fragment i and the last gap is below the
+// last fragment. Down[i] lines are shown from the top of gap i, after the
+// fragment above it, and Up[i] lines from its bottom, before the fragment
+// below it.
+type Context struct {
+	Down []int
+	Up   []int
+}
+
+// NewContext shows none of the gaps around count fragments.
+func NewContext(count int) Context {
+	return Context{Down: make([]int, count+1), Up: make([]int, count+1)}
+}
+
+// Expand adds the unchanged lines shown by ctx around the fragments of a file.
+// lines is the whole content of one side of the file, old tells which, and
+// must match the fragments, see Check. The fragments of gaps that are shown
+// entirely are merged, first holds the index of the first original fragment
+// of each returned one.
+func Expand(frags []*gitdiff.TextFragment, lines []string, old bool, ctx Context) (expanded []*gitdiff.TextFragment, first []int) {
+	context := func(from, to int) []gitdiff.Line {
+		res := make([]gitdiff.Line, 0, to-from)
+		for _, line := range lines[from:to] {
+			res = append(res, gitdiff.Line{Op: gitdiff.OpContext, Line: line})
+		}
+		return res
+	}
+
+	var cur *gitdiff.TextFragment
+	prevEnd := 0
+	for i, frag := range frags {
+		start, _ := span(frag, old)
+		gap := start - prevEnd
+		down := 0
+		if cur != nil {
+			down = min(ctx.Down[i], gap)
+		}
+		up := min(ctx.Up[i], gap-down)
+
+		if cur != nil && down+up == gap {
+			cur.Lines = append(cur.Lines, context(prevEnd, start)...)
+			cur.Lines = append(cur.Lines, frag.Lines...)
+		} else {
+			if cur != nil {
+				cur.Lines = append(cur.Lines, context(prevEnd, prevEnd+down)...)
+				expanded = append(expanded, cur)
+			}
+			oldStart, _ := span(frag, true)
+			newStart, _ := span(frag, false)
+			cur = &gitdiff.TextFragment{
+				Comment:     frag.Comment,
+				OldPosition: int64(oldStart - up),
+				NewPosition: int64(newStart - up),
+				Lines:       append(context(start-up, start), frag.Lines...),
+			}
+			first = append(first, i)
+		}
+		_, end := span(frag, old)
+		prevEnd = end
+	}
+	if cur != nil {
+		down := min(ctx.Down[len(frags)], len(lines)-prevEnd)
+		cur.Lines = append(cur.Lines, context(prevEnd, prevEnd+down)...)
+		expanded = append(expanded, cur)
+	}
+	for _, frag := range expanded {
+		count(frag)
+	}
+	return expanded, first
+}
+
+// span returns the indices of the first line of frag on one side and of the
+// line after its last.
+func span(frag *gitdiff.TextFragment, old bool) (int, int) {
+	pos, n := frag.NewPosition, frag.NewLines
+	if old {
+		pos, n = frag.OldPosition, frag.OldLines
+	}
+	// git numbers an empty side by the line preceding it
+	if n > 0 {
+		pos--
+	}
+	return int(pos), int(pos + n)
+}
+
+// Check makes sure the lines of each fragment are those of one side of the
+// file, old tells which.
+func Check(frags []*gitdiff.TextFragment, lines []string, old bool) error {
+	prevEnd := 0
+	for _, frag := range frags {
+		start, end := span(frag, old)
+		if start < prevEnd || end > len(lines) {
+			return fmt.Errorf("hunk at line %d is out of the file", start+1)
+		}
+		i := start
+		for _, line := range frag.Lines {
+			if (old && !line.Old()) || (!old && !line.New()) {
+				continue
+			}
+			if lines[i] != line.Line {
+				return fmt.Errorf("line %d differs from the file", i+1)
+			}
+			i++
+		}
+		prevEnd = end
+	}
+	return nil
+}
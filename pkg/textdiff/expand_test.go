@@ -0,0 +1,101 @@
+package textdiff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	new := "1\n2\nx\n4\n5\n6\n7\ny\n9\n"
+	frags := Fragments(old, new, 1)
+	tests := []struct {
+		name      string
+		down, up  []int
+		want      string
+		wantFirst []int
+	}{
+		{
+			name: "nothing", down: []int{0, 0, 0}, up: []int{0, 0, 0},
+			want:      "@@ -2,3 +2,3 @@\n 2\n-3\n+x\n 4\n@@ -7,3 +7,3 @@\n 7\n-8\n+y\n 9\n",
+			wantFirst: []int{0, 1},
+		},
+		{
+			name: "above", down: []int{0, 0, 0}, up: []int{1, 1, 0},
+			want:      "@@ -1,4 +1,4 @@\n 1\n 2\n-3\n+x\n 4\n@@ -6,4 +6,4 @@\n 6\n 7\n-8\n+y\n 9\n",
+			wantFirst: []int{0, 1},
+		},
+		{
+			name: "gap shown entirely", down: []int{0, 2, 0}, up: []int{0, 0, 0},
+			want:      "@@ -2,8 +2,8 @@\n 2\n-3\n+x\n 4\n 5\n 6\n 7\n-8\n+y\n 9\n",
+			wantFirst: []int{0},
+		},
+		{
+			name: "more than the gap", down: []int{0, 1, 0}, up: []int{5, 5, 0},
+			want:      "@@ -1,9 +1,9 @@\n 1\n 2\n-3\n+x\n 4\n 5\n 6\n 7\n-8\n+y\n 9\n",
+			wantFirst: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, first := Expand(frags, SplitLines(new), false, Context{Down: tt.down, Up: tt.up})
+			if s := fragString(got); s != tt.want {
+				t.Errorf("Expand() =\n%s\nwant\n%s", s, tt.want)
+			}
+			if !reflect.DeepEqual(first, tt.wantFirst) {
+				t.Errorf("Expand() first = %v, want %v", first, tt.wantFirst)
+			}
+		})
+	}
+}
+
+// TestExpandMoreContext checks that expanding every gap by n lines gives the
+// fragments of a diff with n more lines of context, from either side.
+func TestExpandMoreContext(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for i := 0; i < 2000; i++ {
+		a, b := randomText(r), randomText(r)
+		frags := Fragments(a, b, 1)
+		if len(frags) == 0 {
+			continue
+		}
+		extra := r.Intn(4)
+		ctx := NewContext(len(frags))
+		for j := range ctx.Down {
+			ctx.Down[j], ctx.Up[j] = extra, extra
+		}
+		old := r.Intn(2) == 0
+		side := b
+		if old {
+			side = a
+		}
+		got, _ := Expand(frags, SplitLines(side), old, ctx)
+		if want := Fragments(a, b, 1+extra); fragString(got) != fragString(want) {
+			t.Fatalf("Expand(%q, %q, old=%v, +%d) =\n%s\nwant\n%s", a, b, old, extra, fragString(got), fragString(want))
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	frags := Fragments("a\nb\nc\n", "a\nB\nc\n", 1)
+	tests := []struct {
+		name    string
+		lines   string
+		old     bool
+		wantErr bool
+	}{
+		{name: "new side", lines: "a\nB\nc\n"},
+		{name: "old side", lines: "a\nb\nc\n", old: true},
+		{name: "other side", lines: "a\nb\nc\n", wantErr: true},
+		{name: "too short", lines: "a\nB\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(frags, SplitLines(tt.lines), tt.old)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
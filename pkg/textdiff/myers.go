@@ -0,0 +1,91 @@
+package textdiff
+
+// differ implements Myers' linear space diff algorithm over interned lines,
+// marking which lines of a were deleted and which lines of b were added.
+type differ struct {
+	a, b    []int
+	deleted []bool
+	added   []bool
+}
+
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo == aHi || bLo == bHi {
+		d.replace(aLo, aHi, bLo, bHi)
+		return
+	}
+
+	x, y := d.middleSnake(aLo, aHi, bLo, bHi)
+	if (x == aLo && y == bLo) || (x == aHi && y == bHi) {
+		// no progress can be made, which shouldn't happen on an optimal path
+		d.replace(aLo, aHi, bLo, bHi)
+		return
+	}
+	d.compare(aLo, x, bLo, y)
+	d.compare(x, aHi, y, bHi)
+}
+
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for i := aLo; i < aHi; i++ {
+		d.deleted[i] = true
+	}
+	for j := bLo; j < bHi; j++ {
+		d.added[j] = true
+	}
+}
+
+// middleSnake runs the forward and reverse searches until they meet and
+// returns a point on an optimal path through the edit graph.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	off := n + m + 1
+	forward := make([]int, 2*off+1)
+	reverse := make([]int, 2*off+1)
+
+	for step := 0; step <= (n+m+1)/2; step++ {
+		for k := -step; k <= step; k += 2 {
+			x := furthest(forward, off, k, step)
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[off+k] = x
+			if odd && delta-k >= -(step-1) && delta-k <= step-1 && x+reverse[off+delta-k] >= n {
+				return aLo + x, bLo + y
+			}
+		}
+
+		for k := -step; k <= step; k += 2 {
+			x := furthest(reverse, off, k, step)
+			y := x - k
+			for x < n && y < m && d.a[aHi-1-x] == d.b[bHi-1-y] {
+				x++
+				y++
+			}
+			reverse[off+k] = x
+			if !odd && delta-k >= -step && delta-k <= step && x+forward[off+delta-k] >= n {
+				return aHi - x, bHi - y
+			}
+		}
+	}
+	return aLo, bLo
+}
+
+// furthest picks the diagonal neighbour to extend on diagonal k.
+func furthest(v []int, off, k, step int) int {
+	if k == -step || (k != step && v[off+k-1] < v[off+k+1]) {
+		return v[off+k+1]
+	}
+	return v[off+k-1] + 1
+}
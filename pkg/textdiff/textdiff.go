@@ -0,0 +1,153 @@
+// Package textdiff computes line based diffs between two texts and shapes
+// them into the same fragments git produces.
+package textdiff
+
+import (
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// Fragments diffs old and new line by line and groups the changes into
+// fragments surrounded by up to context unchanged lines.
+func Fragments(old, new string, context int) []*gitdiff.TextFragment {
+	a, b := SplitLines(old), SplitLines(new)
+	return group(script(a, b, nil), context)
+}
+
+// SplitLines splits s after each newline, keeping the terminators so that a
+// missing newline at the end of the text is preserved.
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// script returns the full edit script turning a into b, deletions before
+// additions within each changed region. Lines are compared by their key, the
+// whole line when it's nil, and unchanged lines are taken from b.
+func script(a, b []string, key func(string) string) []gitdiff.Line {
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		res := make([]int, len(lines))
+		for i, line := range lines {
+			if key != nil {
+				line = key(line)
+			}
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			res[i] = id
+		}
+		return res
+	}
+
+	d := &differ{a: intern(a), b: intern(b)}
+	d.deleted = make([]bool, len(a))
+	d.added = make([]bool, len(b))
+	d.compare(0, len(a), 0, len(b))
+
+	lines := make([]gitdiff.Line, 0, max(len(a), len(b)))
+	for i, j := 0, 0; i < len(a) || j < len(b); {
+		switch {
+		case i < len(a) && d.deleted[i]:
+			lines = append(lines, gitdiff.Line{Op: gitdiff.OpDelete, Line: a[i]})
+			i++
+		case j < len(b) && d.added[j]:
+			lines = append(lines, gitdiff.Line{Op: gitdiff.OpAdd, Line: b[j]})
+			j++
+		default:
+			lines = append(lines, gitdiff.Line{Op: gitdiff.OpContext, Line: b[j]})
+			i++
+			j++
+		}
+	}
+	return lines
+}
+
+// group cuts the edit script into fragments, merging changes that are closer
+// than twice the context.
+func group(lines []gitdiff.Line, context int) []*gitdiff.TextFragment {
+	frags := make([]*gitdiff.TextFragment, 0)
+	var oldLine, newLine int64
+	for i := 0; i < len(lines); {
+		if lines[i].Op == gitdiff.OpContext {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		start := max(0, i-context)
+		end := i
+		for k := i; k < len(lines); k++ {
+			if lines[k].Op != gitdiff.OpContext {
+				end = k + 1
+				continue
+			}
+			if k-end >= 2*context {
+				break
+			}
+		}
+		end = min(len(lines), end+context)
+
+		leading := int64(i - start)
+		frag := &gitdiff.TextFragment{
+			OldPosition: oldLine - leading,
+			NewPosition: newLine - leading,
+			Lines:       lines[start:end],
+		}
+		count(frag)
+		frags = append(frags, frag)
+
+		for _, line := range lines[i:end] {
+			if line.Old() {
+				oldLine++
+			}
+			if line.New() {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return frags
+}
+
+// count sets the line counts of frag from its lines, and turns its positions
+// from the index of its first line on each side into git's line numbers.
+func count(frag *gitdiff.TextFragment) {
+	for _, line := range frag.Lines {
+		switch line.Op {
+		case gitdiff.OpContext:
+			frag.OldLines++
+			frag.NewLines++
+			if frag.LinesAdded == 0 && frag.LinesDeleted == 0 {
+				frag.LeadingContext++
+			} else {
+				frag.TrailingContext++
+			}
+		case gitdiff.OpDelete:
+			frag.OldLines++
+			frag.LinesDeleted++
+			frag.TrailingContext = 0
+		case gitdiff.OpAdd:
+			frag.NewLines++
+			frag.LinesAdded++
+			frag.TrailingContext = 0
+		}
+	}
+	// git numbers an empty side by the line preceding it
+	if frag.OldLines > 0 {
+		frag.OldPosition++
+	}
+	if frag.NewLines > 0 {
+		frag.NewPosition++
+	}
+}
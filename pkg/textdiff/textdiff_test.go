@@ -0,0 +1,155 @@
+package textdiff
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "a", want: []string{"a"}},
+		{in: "a\n", want: []string{"a\n"}},
+		{in: "a\nb", want: []string{"a\n", "b"}},
+		{in: "a\n\nb\n", want: []string{"a\n", "\n", "b\n"}},
+	}
+	for _, tt := range tests {
+		if got := SplitLines(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFragments(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{name: "equal", old: "a\nb\n", new: "a\nb\n", context: 3, want: ""},
+		{
+			name: "changed line", old: "a\nb\nc\n", new: "a\nB\nc\n", context: 1,
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "added file", old: "", new: "a\nb\n", context: 3,
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deleted file", old: "a\nb\n", new: "", context: 3,
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "separate hunks", old: "1\n2\n3\n4\n5\n6\n7\n", new: "x\n2\n3\n4\n5\n6\ny\n", context: 1,
+			want: "@@ -1,2 +1,2 @@\n-1\n+x\n 2\n@@ -6,2 +6,2 @@\n 6\n-7\n+y\n",
+		},
+		{
+			name: "merged hunks", old: "1\n2\n3\n4\n", new: "x\n2\n3\ny\n", context: 1,
+			want: "@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n-4\n+y\n",
+		},
+		{
+			name: "insertion", old: "a\nc\n", new: "a\nb\nc\n", context: 0,
+			want: "@@ -1,0 +2,1 @@\n+b\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Fragments(tt.old, tt.new, tt.context)
+			if s := fragString(got); s != tt.want {
+				t.Errorf("Fragments() =\n%s\nwant\n%s", s, tt.want)
+			}
+		})
+	}
+}
+
+// TestFragmentsApply checks that random fragments are valid and turn the old
+// text into the new one.
+func TestFragmentsApply(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a, b := randomText(r), randomText(r)
+		context := 1 + r.Intn(3)
+		frags := Fragments(a, b, context)
+		for _, frag := range frags {
+			if err := frag.Validate(); err != nil {
+				t.Fatalf("Fragments(%q, %q, %d): %v", a, b, context, err)
+			}
+		}
+		var out bytes.Buffer
+		if err := gitdiff.Apply(&out, strings.NewReader(a), &gitdiff.File{TextFragments: frags}); err != nil {
+			t.Fatalf("applying Fragments(%q, %q, %d): %v", a, b, context, err)
+		}
+		if out.String() != b {
+			t.Fatalf("applying Fragments(%q, %q, %d) = %q", a, b, context, out.String())
+		}
+	}
+}
+
+// TestScriptMinimal checks the edit script against the longest common
+// subsequence of the lines.
+func TestScriptMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 2000; i++ {
+		a, b := SplitLines(randomText(r)), SplitLines(randomText(r))
+		edits := 0
+		for _, line := range script(a, b, nil) {
+			if line.Op != gitdiff.OpContext {
+				edits++
+			}
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("script(%q, %q) has %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// randomText returns up to 40 lines from a small alphabet, so that they repeat,
+// sometimes without a newline at the end.
+func randomText(r *rand.Rand) string {
+	n := r.Intn(40)
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteRune(rune('a' + r.Intn(4)))
+		if i < n-1 || r.Intn(2) == 0 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+func fragString(frags []*gitdiff.TextFragment) string {
+	var b strings.Builder
+	for _, frag := range frags {
+		b.WriteString(frag.Header() + "\n")
+		for _, line := range frag.Lines {
+			b.WriteString(line.String())
+		}
+	}
+	return b.String()
+}
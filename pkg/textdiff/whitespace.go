@@ -0,0 +1,46 @@
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+// IgnoreWhitespace drops the changes of frags that only touch whitespace, like
+// git diff -w. Lines that only differ by whitespace become context, showing
+// their new version. Each fragment is regrouped around its remaining changes
+// with as much context as it had, and left out when it has none.
+func IgnoreWhitespace(frags []*gitdiff.TextFragment) []*gitdiff.TextFragment {
+	res := make([]*gitdiff.TextFragment, 0, len(frags))
+	for _, frag := range frags {
+		var a, b []string
+		for _, line := range frag.Lines {
+			if line.Old() {
+				a = append(a, line.Line)
+			}
+			if line.New() {
+				b = append(b, line.Line)
+			}
+		}
+		oldStart, _ := span(frag, true)
+		newStart, _ := span(frag, false)
+		context := int(max(frag.LeadingContext, frag.TrailingContext))
+		for _, f := range group(script(a, b, stripSpace), context) {
+			f.OldPosition += int64(oldStart)
+			f.NewPosition += int64(newStart)
+			f.Comment = frag.Comment
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,67 @@
+package textdiff
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreWhitespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{name: "indentation only", old: "a\nb\nc\n", new: "a\n  b\nc\n", want: ""},
+		{name: "trailing space only", old: "a\nb\n", new: "a \nb\n", want: ""},
+		{
+			name: "mixed", old: "a\nb\nc\nd\n", new: "a\n  b\nc\nD\n",
+			want: "@@ -3,2 +3,2 @@\n c\n-d\n+D\n",
+		},
+		{
+			name: "real change kept", old: "a\nb\nc\n", new: "a\nx\nc\n",
+			want: "@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := IgnoreWhitespace(Fragments(tt.old, tt.new, 1))
+			if s := fragString(got); s != tt.want {
+				t.Errorf("IgnoreWhitespace() =\n%s\nwant\n%s", s, tt.want)
+			}
+		})
+	}
+}
+
+// TestIgnoreWhitespaceValid checks that the regrouped fragments are valid and
+// match the new side of the file.
+func TestIgnoreWhitespaceValid(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	for i := 0; i < 2000; i++ {
+		a := randomText(r)
+		var b strings.Builder
+		for _, line := range SplitLines(a) {
+			switch r.Intn(6) {
+			case 0:
+				b.WriteString("  " + line)
+			case 1:
+				b.WriteString("z\n")
+			case 2:
+			default:
+				b.WriteString(line)
+			}
+		}
+		got := IgnoreWhitespace(Fragments(a, b.String(), 1+r.Intn(3)))
+		for _, frag := range got {
+			if err := frag.Validate(); err != nil {
+				t.Fatalf("%q, %q: %v\n%s", a, b.String(), err, fragString(got))
+			}
+			if frag.LinesAdded+frag.LinesDeleted == 0 {
+				t.Fatalf("%q, %q: fragment without changes\n%s", a, b.String(), fragString(got))
+			}
+		}
+		if err := Check(got, SplitLines(b.String()), false); err != nil {
+			t.Fatalf("%q, %q: %v\n%s", a, b.String(), err, fragString(got))
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Package trailer reads the trailers ending a commit message, like
+// Signed-off-by and Co-authored-by.
+package trailer
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Trailer is a "Token: value" line of the last paragraph of a message.
+type Trailer struct {
+	Token string
+	Value string
+}
+
+var trailerLine = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s*(.*)$`)
+
+// Split separates the trailers from the rest of message. The last paragraph
+// holds trailers only when each of its lines is one, a line starting with
+// whitespace continues the trailer before it.
+func Split(message string) (string, []Trailer) {
+	message = strings.TrimRight(message, "\n")
+	// the whole message is a single paragraph when there's no blank line
+	start := strings.LastIndex(message, "\n\n")
+	paragraph := strings.TrimLeft(message[start+1:], "\n")
+
+	var trailers []Trailer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if len(trailers) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		match := trailerLine.FindStringSubmatch(line)
+		if match == nil {
+			return message, nil
+		}
+		trailers = append(trailers, Trailer{Token: match[1], Value: strings.TrimSpace(match[2])})
+	}
+	if start == -1 {
+		return "", trailers
+	}
+	return strings.TrimRight(message[:start], "\n"), trailers
+}
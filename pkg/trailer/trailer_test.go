@@ -0,0 +1,61 @@
+package trailer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		want     string
+		trailers []Trailer
+	}{
+		{name: "empty"},
+		{name: "no trailers", message: "Fix the thing.\n", want: "Fix the thing."},
+		{
+			name:     "trailers only",
+			message:  "Signed-off-by: Ada <a@b.c>\n",
+			trailers: []Trailer{{Token: "Signed-off-by", Value: "Ada <a@b.c>"}},
+		},
+		{
+			name:    "body and trailers",
+			message: "Longer body.\n\nMore body.\n\nSigned-off-by: Ada <a@b.c>\nCo-authored-by: Bob <b@c.d>\n",
+			want:    "Longer body.\n\nMore body.",
+			trailers: []Trailer{
+				{Token: "Signed-off-by", Value: "Ada <a@b.c>"},
+				{Token: "Co-authored-by", Value: "Bob <b@c.d>"},
+			},
+		},
+		{
+			name:     "continuation line",
+			message:  "Body.\n\nReviewed-by: Ada\n <a@b.c>\n",
+			want:     "Body.",
+			trailers: []Trailer{{Token: "Reviewed-by", Value: "Ada <a@b.c>"}},
+		},
+		{
+			name:    "last paragraph not all trailers",
+			message: "Body.\n\nSee the docs\nSigned-off-by: Ada\n",
+			want:    "Body.\n\nSee the docs\nSigned-off-by: Ada",
+		},
+		{
+			name:    "trailer in the middle",
+			message: "Fixes: #12\n\nBody after.",
+			want:    "Fixes: #12\n\nBody after.",
+		},
+		{
+			name:    "token with spaces",
+			message: "Body.\n\nNot a token: value",
+			want:    "Body.\n\nNot a token: value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, trailers := Split(tt.message)
+			if got != tt.want || !reflect.DeepEqual(trailers, tt.trailers) {
+				t.Errorf("Split(%q) = %q, %v, want %q, %v", tt.message, got, trailers, tt.want, tt.trailers)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+// maxCommitRows is how many commits the list shows at once.
+const maxCommitRows = 4
+
+// rebuildScale is how many files the tree grows by before it's rebuilt less
+// often, every flushInterval more. Rebuilding sorts and filters every file, so
+// large inputs would otherwise spend their time rebuilding.
+const rebuildScale = 2000
+
+// rebuildMsg rebuilds the tree with the files that came in since it was last
+// rebuilt.
+type rebuildMsg struct{}
+
+// commit is one of the commits of the input, e.g. of git log -p. A plain diff
+// is a single commit without header.
+type commit struct {
+	header *gitdiff.PatchHeader
+	files  []*gitdiff.File
+}
+
+func (c commit) title() string {
+	if c.header == nil || c.header.Title == "" {
+		return "(no message)"
+	}
+	return c.header.Title
+}
+
+// byline tells the date and author of the commit, those it has.
+func (c commit) byline() string {
+	if c.header == nil {
+		return ""
+	}
+	parts := make([]string, 0, 2)
+	if !c.header.AuthorDate.IsZero() {
+		parts = append(parts, c.header.AuthorDate.Format("2006-01-02"))
+	}
+	if c.header.Author != nil {
+		parts = append(parts, c.header.Author.Name)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// addFiles merges a batch of newly parsed files into the tree of their commit,
+// keeping the currently selected file selected. A new header starts a commit.
+// The tree of the selected commit is rebuilt at a rate that lowers as it
+// grows.
+func (m *mainModel) addFiles(header *gitdiff.PatchHeader, files []*gitdiff.File) tea.Cmd {
+	var cmds []tea.Cmd
+	if len(m.commits) == 0 || m.commits[len(m.commits)-1].header != header {
+		m.commits = append(m.commits, &commit{header: header})
+		cmds = append(cmds, m.resizeTree(), m.resizeDiff())
+		if m.emptyCommit() {
+			cmds = append(cmds, m.applyFilter())
+		}
+	}
+	if len(files) == 0 {
+		return tea.Batch(cmds...)
+	}
+	last := m.commits[len(m.commits)-1]
+	last.files = append(last.files, files...)
+	if last == m.commits[m.commit] {
+		m.files = last.files
+		cmds = append(cmds, m.queueRebuild())
+	}
+	return tea.Batch(cmds...)
+}
+
+// queueRebuild rebuilds the tree now when it's been long enough since the last
+// time, later otherwise.
+func (m *mainModel) queueRebuild() tea.Cmd {
+	if m.rebuildQueued {
+		return nil
+	}
+	interval := flushInterval * time.Duration(1+len(m.files)/rebuildScale)
+	wait := interval - time.Since(m.rebuilt)
+	if wait <= 0 {
+		return m.rebuild()
+	}
+	m.rebuildQueued = true
+	return tea.Tick(wait, func(time.Time) tea.Msg { return rebuildMsg{} })
+}
+
+// rebuild sorts the files of the selected commit and rebuilds the tree.
+func (m *mainModel) rebuild() tea.Cmd {
+	m.rebuilt = time.Now()
+	sortFiles(m.files)
+	return m.applyFilter()
+}
+
+// hasFiles reports whether any commit changed files.
+func (m mainModel) hasFiles() bool {
+	return slices.ContainsFunc(m.commits, func(c *commit) bool { return len(c.files) > 0 })
+}
+
+// emptyCommit reports whether the selected commit was read entirely without
+// changing files, e.g. a merge.
+func (m mainModel) emptyCommit() bool {
+	done := m.loaded || m.commit < len(m.commits)-1
+	return len(m.files) == 0 && m.header() != nil && done
+}
+
+// header is the selected commit's, nil for a plain diff.
+func (m mainModel) header() *gitdiff.PatchHeader {
+	if len(m.commits) == 0 {
+		return nil
+	}
+	return m.commits[m.commit].header
+}
+
+// stepCommit shows the files of the next or previous commit.
+func (m *mainModel) stepCommit(step int) tea.Cmd {
+	i := m.commit + step
+	if i < 0 || i >= len(m.commits) {
+		return nil
+	}
+	m.commit = i
+	m.files = m.commits[i].files
+	m.current = nil
+	// the matches are in the files of the other commit
+	cmds := []tea.Cmd{m.resizeDiff(), m.clearFind(), m.rebuild()}
+	if m.current == nil && len(m.shown) > 0 {
+		cmds = append(cmds, m.selectFile(m.fileTree.OrderedFiles()[0]))
+	}
+	return tea.Batch(cmds...)
+}
+
+// resizeTree fits the tree in the sidebar, below the list of commits.
+func (m *mainModel) resizeTree() tea.Cmd {
+	return m.fileTree.SetSize(m.sidebarWidth(), m.height-footerHeight-headerHeight-searchHeight-m.commitsHeight())
+}
+
+// commitsHeight is the height of the list of commits, which is only shown when
+// there are several.
+func (m mainModel) commitsHeight() int {
+	if len(m.commits) < 2 {
+		return 0
+	}
+	// the position, two lines per commit and the border
+	return 1 + 2*min(len(m.commits), maxCommitRows) + 1
+}
+
+// commitsView lists the commits around the selected one, with their title,
+// date and author.
+func (m mainModel) commitsView() string {
+	if m.commitsHeight() == 0 {
+		return ""
+	}
+	theme := config.Get().Theme
+	width := m.sidebarWidth()
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+
+	rows := []string{utils.TruncateString(muted.Render(fmt.Sprintf(" commit %d/%d", m.commit+1, len(m.commits))), width)}
+	count := min(len(m.commits), maxCommitRows)
+	start := max(0, min(m.commit-count/2, len(m.commits)-count))
+	for i := start; i < start+count; i++ {
+		c := m.commits[i]
+		base := lipgloss.NewStyle().Width(width)
+		if i == m.commit {
+			base = base.Background(lipgloss.Color(theme.SelectedBackground))
+		}
+		rows = append(rows,
+			base.Bold(i == m.commit).Render(utils.TruncateString(" "+c.title(), width)),
+			base.Foreground(lipgloss.Color(theme.Muted)).Render(utils.TruncateString(" "+c.byline(), width)))
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.NormalBorder(), false, false, true, false).
+		BorderForeground(lipgloss.Color(theme.Border)).
+		Render(strings.Join(rows, "\n"))
+}
@@ -0,0 +1,186 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/filefilter"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+// filterBar edits the filter narrowing the files of the tree. The filter is
+// applied as it's typed, as long as it's valid.
+type filterBar struct {
+	input   textinput.Model
+	editing bool
+	filter  filefilter.Filter
+	// previous is the filter to go back to when editing is cancelled.
+	previous filefilter.Filter
+	err      error
+}
+
+func newFilterBar(filter filefilter.Filter) filterBar {
+	theme := config.Get().Theme
+	input := textinput.New()
+	input.Prompt = "󰈲 "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	input.Placeholder = "is:added *.go !**/*_test.go"
+	input.PlaceholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	return filterBar{input: input, filter: filter}
+}
+
+func (m *mainModel) openFilter() tea.Cmd {
+	m.filterBar.editing = true
+	m.filterBar.previous = m.filterBar.filter
+	m.filterBar.err = nil
+	m.filterBar.input.Width = m.sidebarWidth() - 5
+	m.filterBar.input.SetValue(m.filterBar.filter.String())
+	m.filterBar.input.CursorEnd()
+	dfCmd := m.resizeDiff()
+	return tea.Batch(dfCmd, m.filterBar.input.Focus())
+}
+
+func (m *mainModel) closeFilter() tea.Cmd {
+	m.filterBar.editing = false
+	m.filterBar.input.Blur()
+	return m.resizeDiff()
+}
+
+func (m mainModel) filterUpdate(msg tea.KeyMsg) (mainModel, []tea.Cmd) {
+	km := keys.Get()
+	seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextFilter))
+	if !ok {
+		return m, nil
+	}
+	var cmds []tea.Cmd
+	switch {
+	case key.Matches(seq, km.ForceQuit):
+		return m, []tea.Cmd{tea.Quit}
+	case key.Matches(seq, km.CancelFilter):
+		cmds = append(cmds, m.closeFilter(), m.setFilter(m.filterBar.previous))
+	case key.Matches(seq, km.ApplyFilter):
+		if m.filterBar.err == nil {
+			cmds = append(cmds, m.closeFilter())
+		}
+	default:
+		text := m.filterBar.input.Value()
+		var cmd tea.Cmd
+		m.filterBar.input, cmd = m.filterBar.input.Update(msg)
+		cmds = append(cmds, cmd)
+		if m.filterBar.input.Value() == text {
+			break
+		}
+		filter, err := filefilter.Parse(m.filterBar.input.Value())
+		m.filterBar.err = err
+		if err == nil {
+			cmds = append(cmds, m.setFilter(filter))
+		}
+	}
+	return m, cmds
+}
+
+// setFilter narrows the tree to the files kept by filter.
+func (m *mainModel) setFilter(filter filefilter.Filter) tea.Cmd {
+	if filter.String() == m.filterBar.filter.String() {
+		return nil
+	}
+	m.filterBar.filter = filter
+	// the matches may be in files that are now hidden
+	return tea.Batch(m.clearFind(), m.applyFilter())
+}
+
+// applyFilter rebuilds the tree from the files kept by the filter, marking
+// those that only change whitespace when it's hidden. When the current file is
+// filtered out the first remaining file is shown instead.
+func (m *mainModel) applyFilter() tea.Cmd {
+	m.shown = m.filterBar.filter.Apply(m.files, m.isGenerated)
+	whitespaceOnly := make(map[*gitdiff.File]bool)
+	if m.diffViewer.IgnoresWhitespace() {
+		for _, file := range m.shown {
+			if m.diffViewer.WhitespaceOnly(file) {
+				whitespaceOnly[file] = true
+			}
+		}
+	}
+	m.whitespaceOnly = len(whitespaceOnly)
+	m.fileTree = m.fileTree.SetWhitespaceOnly(func(file *gitdiff.File) bool { return whitespaceOnly[file] })
+	m.fileTree = m.fileTree.SetFiles(m.shown)
+	if m.searching {
+		m.filterResults()
+	}
+	if len(m.shown) == 0 {
+		// the diff tells it's loading until the first files come in, unless
+		// the commit has none
+		if len(m.files) == 0 && !m.emptyCommit() {
+			return nil
+		}
+		m.current = nil
+		var cmd tea.Cmd
+		m.diffViewer, cmd = m.diffViewer.SetFilePatch(nil)
+		return cmd
+	}
+	if m.current != nil && !slices.Contains(m.shown, m.current) {
+		return m.selectFile(m.fileTree.OrderedFiles()[0])
+	}
+	return m.showSelectedFile()
+}
+
+// filterHintView lists the kinds of change to filter by, or why the filter is
+// invalid. The placeholder of the input shows the rest of the syntax.
+func (m mainModel) filterHintView() string {
+	theme := config.Get().Theme
+	width := m.sidebarWidth() - 2
+	if m.filterBar.err != nil {
+		style := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Deleted))
+		return utils.TruncateString(style.Render(" "+m.filterBar.err.Error()), width)
+	}
+	kinds := make([]string, len(filefilter.Kinds))
+	for i, kind := range filefilter.Kinds {
+		kinds[i] = string(kind)
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Width(width).PaddingLeft(1)
+	return style.Render("is: " + strings.Join(kinds, " "))
+}
+
+// treeView shows the file tree, or that the filter hides every file.
+func (m mainModel) treeView() string {
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted))
+	switch {
+	case len(m.shown) == 0 && len(m.files) > 0:
+		return utils.TruncateString(style.Render(" no matching files"), m.sidebarWidth())
+	case m.emptyCommit():
+		return utils.TruncateString(style.Render(" no files changed"), m.sidebarWidth())
+	}
+	return m.fileTree.View()
+}
+
+// filterView tells how many files the filter hides, it's empty without a filter.
+func (m mainModel) filterView() string {
+	if m.filterBar.filter.IsZero() {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted))
+	return style.Render(fmt.Sprintf("  showing %d of %d files", len(m.shown), len(m.files)))
+}
+
+// whitespaceView tells that whitespace changes are hidden, and how many files
+// only changed whitespace.
+func (m mainModel) whitespaceView() string {
+	if !m.diffViewer.IgnoresWhitespace() {
+		return ""
+	}
+	text := "  whitespace hidden"
+	if m.whitespaceOnly > 0 {
+		text += fmt.Sprintf(" · %d whitespace only", m.whitespaceOnly)
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted)).Render(text)
+}
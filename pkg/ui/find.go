@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/diffsearch"
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+	"github.com/dlvhdr/diffnav/pkg/worddiff"
+)
+
+// findStatusHeight is the line above the matches telling their count and filter.
+const findStatusHeight = 1
+
+// finder searches the content of the diff, in the current file or in all of
+// them. The matches are kept after the search is closed to cycle through them.
+type finder struct {
+	input   textinput.Model
+	typing  bool
+	all     bool
+	filter  diffsearch.Filter
+	matches []diffsearch.Match
+	cursor  int
+	// offset is the first match shown in the list.
+	offset int
+}
+
+func newFinder() finder {
+	theme := config.Get().Theme
+	input := textinput.New()
+	input.Prompt = " "
+	input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	input.PlaceholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	return finder{input: input}
+}
+
+func (m *mainModel) openFind(all bool) tea.Cmd {
+	m.find.typing = true
+	m.find.all = all
+	m.find.matches = nil
+	m.find.cursor, m.find.offset = 0, 0
+	m.find.input.Placeholder = "Find in file"
+	if all {
+		m.find.input.Placeholder = "Find in all files"
+	}
+	m.find.input.Width = m.sidebarWidth() - 5
+	m.find.input.SetValue("")
+	dfCmd := m.resizeDiff()
+	return tea.Batch(dfCmd, m.find.input.Focus())
+}
+
+func (m *mainModel) closeFind() tea.Cmd {
+	m.find.typing = false
+	m.find.input.Blur()
+	return m.resizeDiff()
+}
+
+func (m mainModel) findUpdate(msg tea.KeyMsg) (mainModel, []tea.Cmd) {
+	km := keys.Get()
+	seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextFind))
+	if !ok {
+		return m, nil
+	}
+	var cmds []tea.Cmd
+	switch {
+	case key.Matches(seq, km.ForceQuit):
+		return m, []tea.Cmd{tea.Quit}
+	case key.Matches(seq, km.FindCancel):
+		cmds = append(cmds, m.closeFind(), m.clearFind(), m.cancelPreview())
+	case key.Matches(seq, km.FindSelect):
+		cmds = append(cmds, m.closeFind())
+		if len(m.find.matches) == 0 {
+			cmds = append(cmds, m.clearFind(), m.cancelPreview())
+			break
+		}
+		match := m.find.matches[m.find.cursor]
+		cmds = append(cmds, m.selectResult(match.File))
+		m.diffViewer = m.diffViewer.ShowMatch(match)
+	case key.Matches(seq, km.FindDown):
+		cmds = append(cmds, m.moveFindCursor(1))
+	case key.Matches(seq, km.FindUp):
+		cmds = append(cmds, m.moveFindCursor(-1))
+	case key.Matches(seq, km.FindFilter):
+		m.find.filter = m.find.filter.Next()
+		cmds = append(cmds, m.runFind())
+	default:
+		query := m.find.input.Value()
+		var cmd tea.Cmd
+		m.find.input, cmd = m.find.input.Update(msg)
+		cmds = append(cmds, cmd)
+		if m.find.input.Value() != query {
+			cmds = append(cmds, m.runFind())
+		}
+	}
+	return m, cmds
+}
+
+// runFind searches for the query and previews its first match.
+func (m *mainModel) runFind() tea.Cmd {
+	query := diffsearch.Query{Text: m.find.input.Value(), Filter: m.find.filter}
+	files := []*gitdiff.File{m.current}
+	if m.find.all {
+		files = m.fileTree.OrderedFiles()
+	}
+	// collapsed diffs have no lines to show the matches on
+	files = slices.DeleteFunc(files, m.diffViewer.IsCollapsed)
+	m.find.matches = m.diffViewer.Search(files, query)
+	m.find.cursor, m.find.offset = 0, 0
+
+	var cmd tea.Cmd
+	m.diffViewer, cmd = m.diffViewer.SetSearch(query)
+	if len(m.find.matches) == 0 {
+		return tea.Batch(cmd, m.cancelPreview())
+	}
+	return tea.Batch(cmd, m.previewMatch())
+}
+
+// moveFindCursor moves through the list of matches while typing.
+func (m *mainModel) moveFindCursor(step int) tea.Cmd {
+	if len(m.find.matches) == 0 {
+		return nil
+	}
+	m.find.cursor = max(0, min(len(m.find.matches)-1, m.find.cursor+step))
+	height := m.findResultsHeight()
+	if m.find.cursor < m.find.offset {
+		m.find.offset = m.find.cursor
+	} else if m.find.cursor >= m.find.offset+height {
+		m.find.offset = m.find.cursor - height + 1
+	}
+	return m.previewMatch()
+}
+
+// previewMatch shows the match under the cursor without selecting its file.
+func (m *mainModel) previewMatch() tea.Cmd {
+	match := m.find.matches[m.find.cursor]
+	cmd := m.preview(match.File)
+	m.diffViewer = m.diffViewer.ShowMatch(match)
+	return cmd
+}
+
+// stepMatch goes to the next or previous match, wrapping around, and selects
+// its file.
+func (m *mainModel) stepMatch(step int) tea.Cmd {
+	count := len(m.find.matches)
+	if count == 0 {
+		return nil
+	}
+	m.find.cursor = (m.find.cursor + step + count) % count
+	match := m.find.matches[m.find.cursor]
+	cmd := m.selectFile(match.File)
+	m.diffViewer = m.diffViewer.ShowMatch(match)
+	return cmd
+}
+
+// clearFind forgets the matches and removes their highlights.
+func (m *mainModel) clearFind() tea.Cmd {
+	m.find.matches = nil
+	var cmd tea.Cmd
+	m.diffViewer, cmd = m.diffViewer.SetSearch(diffsearch.Query{})
+	return cmd
+}
+
+func (m mainModel) findResultsHeight() int {
+	return max(1, m.height-footerHeight-headerHeight-searchHeight-findStatusHeight)
+}
+
+// findResultsView lists the matches as file:line followed by the line.
+func (m mainModel) findResultsView() string {
+	theme := config.Get().Theme
+	width := config.Get().Sidebar.SearchWidth - 2
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+
+	status := fmt.Sprintf(" %d matches · %s", len(m.find.matches), m.find.filter)
+	if key := keys.Get().FindFilter.Help().Key; key != "" {
+		status += " (" + key + ")"
+	}
+	rows := []string{utils.TruncateString(muted.Render(status), width)}
+	end := min(len(m.find.matches), m.find.offset+m.findResultsHeight())
+	for i := m.find.offset; i < end; i++ {
+		match := m.find.matches[i]
+		base := lipgloss.NewStyle()
+		if i == m.find.cursor {
+			base = base.Background(lipgloss.Color(theme.SelectedBackground)).Bold(true)
+		}
+		sign, signStyle := " ", base
+		switch match.Op {
+		case gitdiff.OpAdd:
+			sign, signStyle = "+", base.Foreground(lipgloss.Color(theme.Added))
+		case gitdiff.OpDelete:
+			sign, signStyle = "-", base.Foreground(lipgloss.Color(theme.Deleted))
+		}
+		loc := fmt.Sprintf("%s:%d", filepath.Base(filenode.GetFileName(match.File)), match.Line())
+		text, ranges := snippet(match)
+		matched := base.Foreground(lipgloss.Color(theme.SearchMatch)).Background(lipgloss.Color(theme.SearchMatchBackground))
+		row := base.Render(" ") + signStyle.Render(sign) + base.Render(" ") +
+			base.Foreground(lipgloss.Color(theme.Accent)).Render(loc) + base.Render("  ") +
+			highlightMatches(text, runePositions(text, ranges), base, matched)
+		rows = append(rows, utils.TruncateString(row, width))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// snippet returns the matched line without its indentation.
+func snippet(match diffsearch.Match) (string, []worddiff.Range) {
+	text := strings.TrimLeft(match.Text, " \t")
+	shift := len(match.Text) - len(text)
+	ranges := make([]worddiff.Range, 0, len(match.Ranges))
+	for _, r := range match.Ranges {
+		if r.End > shift {
+			ranges = append(ranges, worddiff.Range{Start: max(0, r.Start-shift), End: r.End - shift})
+		}
+	}
+	return strings.ReplaceAll(text, "\t", " "), ranges
+}
+
+// runePositions converts byte ranges of text to the indices of the runes in them.
+func runePositions(text string, ranges []worddiff.Range) []int {
+	positions := make([]int, 0)
+	i, r := 0, 0
+	for offset := range text {
+		for r < len(ranges) && ranges[r].End <= offset {
+			r++
+		}
+		if r < len(ranges) && ranges[r].Start <= offset {
+			positions = append(positions, i)
+		}
+		i++
+	}
+	return positions
+}
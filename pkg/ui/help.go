@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+)
+
+const helpGroupGap = 4
+
+func helpBoxStyle() lipgloss.Style {
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(config.Get().Theme.Border)).
+		Padding(0, 2)
+}
+
+// openHelp shows the full help, laid out for the current size.
+func (m *mainModel) openHelp() {
+	m.showingHelp = true
+	m.helpVp.GotoTop()
+	m.resizeHelp()
+}
+
+// resizeHelp lays the help groups out in as many columns as fit, the rest of
+// the help scrolls when it's taller than the screen.
+func (m *mainModel) resizeHelp() {
+	frame := helpBoxStyle()
+	content := helpContent(m.width - frame.GetHorizontalFrameSize())
+	m.helpVp.Width = lipgloss.Width(content)
+	m.helpVp.Height = max(1, min(lipgloss.Height(content), m.height-footerHeight-headerHeight-frame.GetVerticalFrameSize()))
+	m.helpVp.SetContent(content)
+}
+
+func (m mainModel) helpView() string {
+	return lipgloss.Place(m.width, m.height-footerHeight-headerHeight, lipgloss.Center, lipgloss.Center,
+		helpBoxStyle().Render(m.helpVp.View()))
+}
+
+// helpContent renders a block per help group and flows them into rows no
+// wider than width.
+func helpContent(width int) string {
+	blocks := make([]string, 0)
+	for _, group := range keys.Get().FullHelp() {
+		if len(group.Bindings) > 0 {
+			blocks = append(blocks, helpGroupView(group))
+		}
+	}
+
+	rows := make([]string, 0)
+	row := make([]string, 0)
+	rowWidth := 0
+	for _, block := range blocks {
+		w := lipgloss.Width(block)
+		if len(row) > 0 && rowWidth+helpGroupGap+w > width {
+			rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...), "")
+			row, rowWidth = row[:0:0], 0
+		}
+		if len(row) > 0 {
+			row = append(row, strings.Repeat(" ", helpGroupGap))
+			rowWidth += helpGroupGap
+		}
+		row = append(row, block)
+		rowWidth += w
+	}
+	rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Top, row...))
+	return lipgloss.JoinVertical(lipgloss.Left, rows...)
+}
+
+func helpGroupView(group keys.HelpGroup) string {
+	theme := config.Get().Theme
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent)).Bold(true)
+	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Text))
+	descStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+
+	keyWidth := 0
+	for _, b := range group.Bindings {
+		keyWidth = max(keyWidth, lipgloss.Width(b.Help().Key))
+	}
+	lines := []string{titleStyle.Render(string(group.Title))}
+	for _, b := range group.Bindings {
+		lines = append(lines, keyStyle.Width(keyWidth).Render(b.Help().Key)+"  "+descStyle.Render(b.Help().Desc))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
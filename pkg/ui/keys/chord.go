@@ -0,0 +1,59 @@
+package keys
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Sequence is one or more key presses separated by spaces, like "g g". It's
+// a tea.Msg and a fmt.Stringer so it can be dispatched and matched against
+// bindings with key.Matches.
+type Sequence string
+
+func (s Sequence) String() string {
+	return string(s)
+}
+
+// Press returns the sequence of a single key press.
+func Press(msg tea.KeyMsg) Sequence {
+	if msg.Type == tea.KeySpace {
+		return "space"
+	}
+	return Sequence(msg.String())
+}
+
+// Chord collects the key presses of bindings made of several keys.
+type Chord struct {
+	pending []string
+}
+
+// Feed adds a key press. While the presses so far are the start of a longer
+// binding it returns false, otherwise it returns them and starts over. When
+// the press doesn't complete the pending ones into a binding, they're dropped
+// and the press is fed again on its own.
+func (c *Chord) Feed(msg tea.KeyMsg, bindings []key.Binding) (Sequence, bool) {
+	presses := append(slices.Clone(c.pending), Press(msg).String())
+	seq := strings.Join(presses, " ")
+	bound := false
+	for _, b := range bindings {
+		if !b.Enabled() {
+			continue
+		}
+		for _, k := range b.Keys() {
+			if strings.HasPrefix(k, seq+" ") {
+				c.pending = presses
+				return "", false
+			}
+			bound = bound || k == seq
+		}
+	}
+	if len(c.pending) > 0 && !bound {
+		c.pending = nil
+		return c.Feed(msg, bindings)
+	}
+	c.pending = nil
+	return Sequence(seq), true
+}
@@ -0,0 +1,559 @@
+// Package keys holds the key bindings of the UI. They can be remapped from the
+// config file, and may be sequences of key presses like "g g".
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+)
+
+// Context is a mode of the UI with its own set of bindings. Bindings only
+// conflict with bindings of the same context.
+type Context string
+
+const (
+	ContextMain      Context = "main"
+	ContextSearch    Context = "search"
+	ContextSelection Context = "selection"
+	ContextComment   Context = "comment"
+	ContextFind      Context = "find"
+	ContextFilter    Context = "filter"
+	ContextHelp      Context = "help"
+)
+
+// Group is a section of the full help.
+type Group string
+
+const (
+	GroupNavigation Group = "Navigation"
+	GroupTree       Group = "Tree"
+	GroupDiff       Group = "Diff"
+	GroupSearch     Group = "Search"
+	GroupReview     Group = "Review"
+)
+
+type KeyMap struct {
+	Up               key.Binding
+	Down             key.Binding
+	NextCommit       key.Binding
+	PrevCommit       key.Binding
+	Collapse         key.Binding
+	Expand           key.Binding
+	ToggleDir        key.Binding
+	ToggleViewed     key.Binding
+	FilterFiles      key.Binding
+	HalfPageDown     key.Binding
+	HalfPageUp       key.Binding
+	PageDown         key.Binding
+	PageUp           key.Binding
+	NextHunk         key.Binding
+	PrevHunk         key.Binding
+	ExpandDiff       key.Binding
+	CycleView        key.Binding
+	ExpandAbove      key.Binding
+	ExpandBelow      key.Binding
+	WholeFile        key.Binding
+	IgnoreWhitespace key.Binding
+	ToggleMessage    key.Binding
+	Comment          key.Binding
+	ToggleFileTree   key.Binding
+	Search           key.Binding
+	FindInFile       key.Binding
+	FindInAll        key.Binding
+	NextMatch        key.Binding
+	PrevMatch        key.Binding
+	ClearFind        key.Binding
+	Help             key.Binding
+	Quit             key.Binding
+	// ForceQuit quits from every context.
+	ForceQuit key.Binding
+
+	HelpDown  key.Binding
+	HelpUp    key.Binding
+	CloseHelp key.Binding
+
+	SearchDown   key.Binding
+	SearchUp     key.Binding
+	SearchSelect key.Binding
+	SearchCancel key.Binding
+
+	FindDown   key.Binding
+	FindUp     key.Binding
+	FindSelect key.Binding
+	FindCancel key.Binding
+	FindFilter key.Binding
+
+	ApplyFilter  key.Binding
+	CancelFilter key.Binding
+
+	SelectDown    key.Binding
+	SelectUp      key.Binding
+	ExtendDown    key.Binding
+	ExtendUp      key.Binding
+	SwitchSide    key.Binding
+	WriteComment  key.Binding
+	StopSelecting key.Binding
+
+	SaveComment   key.Binding
+	CancelComment key.Binding
+}
+
+func Default() KeyMap {
+	return KeyMap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k", "ctrl+p"),
+			key.WithHelp("↑/k", "prev file"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j", "ctrl+n"),
+			key.WithHelp("↓/j", "next file"),
+		),
+		NextCommit: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "next commit"),
+		),
+		PrevCommit: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "prev commit"),
+		),
+		Collapse: key.NewBinding(
+			key.WithKeys("h", "left"),
+			key.WithHelp("h", "collapse dir"),
+		),
+		Expand: key.NewBinding(
+			key.WithKeys("l", "right"),
+			key.WithHelp("l", "expand dir"),
+		),
+		ToggleDir: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "toggle dir"),
+		),
+		ToggleViewed: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "mark viewed"),
+		),
+		FilterFiles: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "filter files"),
+		),
+		HalfPageDown: key.NewBinding(
+			key.WithKeys("ctrl+d", "d"),
+			key.WithHelp("ctrl+d", "diff down"),
+		),
+		HalfPageUp: key.NewBinding(
+			key.WithKeys("ctrl+u", "u"),
+			key.WithHelp("ctrl+u", "diff up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown", "space", "f"),
+			key.WithHelp("f/pgdn", "diff page down"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup", "b"),
+			key.WithHelp("b/pgup", "diff page up"),
+		),
+		NextHunk: key.NewBinding(
+			key.WithKeys("] c"),
+			key.WithHelp("]c", "next hunk"),
+		),
+		PrevHunk: key.NewBinding(
+			key.WithKeys("[ c"),
+			key.WithHelp("[c", "prev hunk"),
+		),
+		ExpandDiff: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "show collapsed diff"),
+		),
+		CycleView: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "cycle unified/side-by-side/auto"),
+		),
+		ExpandAbove: key.NewBinding(
+			key.WithKeys("{"),
+			key.WithHelp("{", "expand context above hunk"),
+		),
+		ExpandBelow: key.NewBinding(
+			key.WithKeys("}"),
+			key.WithHelp("}", "expand context below hunk"),
+		),
+		WholeFile: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "toggle whole file"),
+		),
+		IgnoreWhitespace: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "hide whitespace changes"),
+		),
+		ToggleMessage: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "toggle commit message"),
+		),
+		Comment: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "comment"),
+		),
+		ToggleFileTree: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "toggle file tree"),
+		),
+		Search: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "search files"),
+		),
+		FindInFile: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "find in file"),
+		),
+		FindInAll: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "find in all files"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "prev match"),
+		),
+		ClearFind: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "clear matches"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("q"),
+			key.WithHelp("q", "quit"),
+		),
+		ForceQuit: key.NewBinding(
+			key.WithKeys("ctrl+c"),
+			key.WithHelp("ctrl+c", "quit from anywhere"),
+		),
+
+		HelpDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "scroll help down"),
+		),
+		HelpUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "scroll help up"),
+		),
+		CloseHelp: key.NewBinding(
+			key.WithKeys("?", "esc", "q"),
+			key.WithHelp("?/esc", "close help"),
+		),
+
+		SearchDown: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓/ctrl+n", "next result"),
+		),
+		SearchUp: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑/ctrl+p", "prev result"),
+		),
+		SearchSelect: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "open file"),
+		),
+		SearchCancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "close search"),
+		),
+
+		FindDown: key.NewBinding(
+			key.WithKeys("down", "ctrl+n"),
+			key.WithHelp("↓/ctrl+n", "next match"),
+		),
+		FindUp: key.NewBinding(
+			key.WithKeys("up", "ctrl+p"),
+			key.WithHelp("↑/ctrl+p", "prev match"),
+		),
+		FindSelect: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "go to match"),
+		),
+		FindCancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel find"),
+		),
+		FindFilter: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "find in all/added/removed lines"),
+		),
+
+		ApplyFilter: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "apply filter"),
+		),
+		CancelFilter: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "undo filter changes"),
+		),
+
+		SelectDown: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("↓/j", "next line"),
+		),
+		SelectUp: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("↑/k", "prev line"),
+		),
+		ExtendDown: key.NewBinding(
+			key.WithKeys("J", "shift+down"),
+			key.WithHelp("J", "extend down"),
+		),
+		ExtendUp: key.NewBinding(
+			key.WithKeys("K", "shift+up"),
+			key.WithHelp("K", "extend up"),
+		),
+		SwitchSide: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch side"),
+		),
+		WriteComment: key.NewBinding(
+			key.WithKeys("c", "enter"),
+			key.WithHelp("c", "write comment"),
+		),
+		StopSelecting: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "stop selecting"),
+		),
+
+		SaveComment: key.NewBinding(
+			key.WithKeys("ctrl+s"),
+			key.WithHelp("ctrl+s", "save"),
+		),
+		CancelComment: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+	}
+}
+
+var current = Default()
+
+// Get returns the key bindings in use.
+func Get() KeyMap {
+	return current
+}
+
+// Set replaces the key bindings in use, it's meant to be called once on startup.
+func Set(k KeyMap) {
+	current = k
+}
+
+// ShortHelp returns the bindings shown in the footer.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Help, k.Up, k.Down, k.Collapse, k.Expand, k.ToggleViewed, k.HalfPageDown, k.HalfPageUp, k.NextHunk, k.PrevHunk, k.Comment, k.ToggleFileTree, k.Search, k.Quit}
+}
+
+// Bindings returns the bindings active in ctx.
+func (k KeyMap) Bindings(ctx Context) []key.Binding {
+	bindings := make([]key.Binding, 0)
+	for _, a := range k.actions() {
+		if a.context == ctx || a.binding == &k.ForceQuit {
+			bindings = append(bindings, *a.binding)
+		}
+	}
+	return bindings
+}
+
+// HelpGroup is a section of the full help.
+type HelpGroup struct {
+	Title    Group
+	Bindings []key.Binding
+}
+
+// FullHelp returns every bound action, grouped by what it acts on.
+func (k KeyMap) FullHelp() []HelpGroup {
+	groups := []HelpGroup{{Title: GroupNavigation}, {Title: GroupTree}, {Title: GroupDiff}, {Title: GroupSearch}, {Title: GroupReview}}
+	for _, a := range k.actions() {
+		if !a.binding.Enabled() {
+			continue
+		}
+		i := slices.IndexFunc(groups, func(g HelpGroup) bool { return g.Title == a.group })
+		groups[i].Bindings = append(groups[i].Bindings, *a.binding)
+	}
+	return groups
+}
+
+// action names a binding in the config file.
+type action struct {
+	context Context
+	name    string
+	group   Group
+	binding *key.Binding
+}
+
+func (k *KeyMap) actions() []action {
+	return []action{
+		{ContextMain, "up", GroupNavigation, &k.Up},
+		{ContextMain, "down", GroupNavigation, &k.Down},
+		{ContextMain, "nextCommit", GroupNavigation, &k.NextCommit},
+		{ContextMain, "prevCommit", GroupNavigation, &k.PrevCommit},
+		{ContextMain, "toggleFileTree", GroupNavigation, &k.ToggleFileTree},
+		{ContextMain, "help", GroupNavigation, &k.Help},
+		{ContextMain, "quit", GroupNavigation, &k.Quit},
+		{ContextMain, "forceQuit", GroupNavigation, &k.ForceQuit},
+		{ContextHelp, "down", GroupNavigation, &k.HelpDown},
+		{ContextHelp, "up", GroupNavigation, &k.HelpUp},
+		{ContextHelp, "close", GroupNavigation, &k.CloseHelp},
+		{ContextMain, "collapse", GroupTree, &k.Collapse},
+		{ContextMain, "expand", GroupTree, &k.Expand},
+		{ContextMain, "toggleDir", GroupTree, &k.ToggleDir},
+		{ContextMain, "toggleViewed", GroupTree, &k.ToggleViewed},
+		{ContextMain, "filterFiles", GroupTree, &k.FilterFiles},
+		{ContextFilter, "apply", GroupTree, &k.ApplyFilter},
+		{ContextFilter, "cancel", GroupTree, &k.CancelFilter},
+		{ContextMain, "halfPageDown", GroupDiff, &k.HalfPageDown},
+		{ContextMain, "halfPageUp", GroupDiff, &k.HalfPageUp},
+		{ContextMain, "pageDown", GroupDiff, &k.PageDown},
+		{ContextMain, "pageUp", GroupDiff, &k.PageUp},
+		{ContextMain, "nextHunk", GroupDiff, &k.NextHunk},
+		{ContextMain, "prevHunk", GroupDiff, &k.PrevHunk},
+		{ContextMain, "expandDiff", GroupDiff, &k.ExpandDiff},
+		{ContextMain, "cycleView", GroupDiff, &k.CycleView},
+		{ContextMain, "expandAbove", GroupDiff, &k.ExpandAbove},
+		{ContextMain, "expandBelow", GroupDiff, &k.ExpandBelow},
+		{ContextMain, "wholeFile", GroupDiff, &k.WholeFile},
+		{ContextMain, "ignoreWhitespace", GroupDiff, &k.IgnoreWhitespace},
+		{ContextMain, "toggleMessage", GroupDiff, &k.ToggleMessage},
+		{ContextMain, "search", GroupSearch, &k.Search},
+		{ContextSearch, "down", GroupSearch, &k.SearchDown},
+		{ContextSearch, "up", GroupSearch, &k.SearchUp},
+		{ContextSearch, "select", GroupSearch, &k.SearchSelect},
+		{ContextSearch, "cancel", GroupSearch, &k.SearchCancel},
+		{ContextMain, "findInFile", GroupSearch, &k.FindInFile},
+		{ContextMain, "findInAll", GroupSearch, &k.FindInAll},
+		{ContextMain, "nextMatch", GroupSearch, &k.NextMatch},
+		{ContextMain, "prevMatch", GroupSearch, &k.PrevMatch},
+		{ContextMain, "clearFind", GroupSearch, &k.ClearFind},
+		{ContextFind, "down", GroupSearch, &k.FindDown},
+		{ContextFind, "up", GroupSearch, &k.FindUp},
+		{ContextFind, "select", GroupSearch, &k.FindSelect},
+		{ContextFind, "cancel", GroupSearch, &k.FindCancel},
+		{ContextFind, "filter", GroupSearch, &k.FindFilter},
+		{ContextMain, "comment", GroupReview, &k.Comment},
+		{ContextSelection, "down", GroupReview, &k.SelectDown},
+		{ContextSelection, "up", GroupReview, &k.SelectUp},
+		{ContextSelection, "extendDown", GroupReview, &k.ExtendDown},
+		{ContextSelection, "extendUp", GroupReview, &k.ExtendUp},
+		{ContextSelection, "switchSide", GroupReview, &k.SwitchSide},
+		{ContextSelection, "comment", GroupReview, &k.WriteComment},
+		{ContextSelection, "cancel", GroupReview, &k.StopSelecting},
+		{ContextComment, "save", GroupReview, &k.SaveComment},
+		{ContextComment, "cancel", GroupReview, &k.CancelComment},
+	}
+}
+
+// New applies the bindings of the config file to the defaults. It fails
+// listing every unknown action and every conflict between bindings.
+func New(overrides config.Keys) (KeyMap, error) {
+	k := Default()
+	actions := k.actions()
+	problems := make([]string, 0)
+	for ctx, bindings := range overrides {
+		if !slices.ContainsFunc(actions, func(a action) bool { return string(a.context) == ctx }) {
+			problems = append(problems, fmt.Sprintf("keys.%s: unknown context, expected main, search, find, filter, selection, comment or help", ctx))
+			continue
+		}
+		for name, keys := range bindings {
+			i := slices.IndexFunc(actions, func(a action) bool {
+				return string(a.context) == ctx && a.name == name
+			})
+			if i == -1 {
+				problems = append(problems, fmt.Sprintf("keys.%s.%s: unknown action", ctx, name))
+				continue
+			}
+			if err := rebind(actions[i].binding, keys); err != nil {
+				problems = append(problems, fmt.Sprintf("keys.%s.%s: %v", ctx, name, err))
+			}
+		}
+	}
+	for _, seq := range k.ForceQuit.Keys() {
+		if strings.Contains(seq, " ") {
+			problems = append(problems, fmt.Sprintf("keys.main.forceQuit: %q can't be a sequence", seq))
+		}
+	}
+	problems = append(problems, conflicts(actions)...)
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return KeyMap{}, errors.New("invalid key bindings:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return k, nil
+}
+
+// rebind replaces the keys of b, an empty list unbinds it.
+func rebind(b *key.Binding, keys []string) error {
+	if len(keys) == 0 {
+		b.Unbind()
+		return nil
+	}
+	seqs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		presses := strings.Fields(k)
+		if len(presses) == 0 {
+			return errors.New("empty key")
+		}
+		seqs = append(seqs, strings.Join(presses, " "))
+	}
+	desc := b.Help().Desc
+	b.SetKeys(seqs...)
+	b.SetHelp(helpKey(seqs), desc)
+	return nil
+}
+
+// helpKey shows the keys the way the defaults do, like "]c" for "] c".
+func helpKey(seqs []string) string {
+	shown := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		presses := strings.Fields(seq)
+		sep := ""
+		for _, p := range presses {
+			if len(p) > 1 {
+				sep = " "
+			}
+		}
+		shown = append(shown, strings.Join(presses, sep))
+	}
+	return strings.Join(shown, "/")
+}
+
+// conflicts lists the bindings of a context that share a key, or where a key
+// is the start of another one's sequence and would never be reached.
+func conflicts(actions []action) []string {
+	problems := make([]string, 0)
+	name := func(a action) string {
+		return string(a.context) + "." + a.name
+	}
+	for i, a := range actions {
+		for _, b := range actions[i+1:] {
+			sameContext := a.context == b.context || a.name == "forceQuit" && a.context == ContextMain
+			if !sameContext {
+				continue
+			}
+			for _, ka := range a.binding.Keys() {
+				for _, kb := range b.binding.Keys() {
+					switch {
+					case ka == kb:
+						problems = append(problems, fmt.Sprintf("keys: %q is bound to both %s and %s", ka, name(a), name(b)))
+					case strings.HasPrefix(kb, ka+" "):
+						problems = append(problems, fmt.Sprintf("keys: %q of %s is the start of %q of %s", ka, name(a), kb, name(b)))
+					case strings.HasPrefix(ka, kb+" "):
+						problems = append(problems, fmt.Sprintf("keys: %q of %s is the start of %q of %s", kb, name(b), ka, name(a)))
+					}
+				}
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,176 @@
+package keys
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides config.Keys
+		// problems are parts of the error, none when the bindings are valid
+		problems []string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name:      "remap",
+			overrides: config.Keys{"main": {"nextHunk": {"g g", "ctrl+j"}}},
+		},
+		{
+			name:      "unbind",
+			overrides: config.Keys{"main": {"down": {}}, "help": {"down": {}}},
+		},
+		{
+			name:      "duplicate",
+			overrides: config.Keys{"main": {"nextHunk": {"j"}}},
+			problems:  []string{`"j" is bound to both main.down and main.nextHunk`},
+		},
+		{
+			name:      "prefix",
+			overrides: config.Keys{"main": {"nextHunk": {"g"}, "prevHunk": {"g g"}}},
+			problems:  []string{`"g" of main.nextHunk is the start of "g g" of main.prevHunk`},
+		},
+		{
+			name:      "same key in other contexts",
+			overrides: config.Keys{"find": {"filter": {"t"}}},
+		},
+		{
+			name:      "force quit conflicts in every context",
+			overrides: config.Keys{"find": {"filter": {"ctrl+c"}}},
+			problems:  []string{`"ctrl+c" is bound to both main.forceQuit and find.filter`},
+		},
+		{
+			name:      "force quit sequence",
+			overrides: config.Keys{"main": {"forceQuit": {"ctrl+x ctrl+c"}}},
+			problems:  []string{`keys.main.forceQuit: "ctrl+x ctrl+c" can't be a sequence`},
+		},
+		{
+			name:      "unknown action and context",
+			overrides: config.Keys{"main": {"jump": {"x"}}, "other": {"down": {"x"}}},
+			problems:  []string{"keys.main.jump: unknown action", "keys.other: unknown context"},
+		},
+		{
+			name:      "empty key",
+			overrides: config.Keys{"main": {"nextHunk": {" "}}},
+			problems:  []string{"keys.main.nextHunk: empty key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.overrides)
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("New() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("New() succeeded, want %q", tt.problems)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("New() = %v, want it to contain %q", err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		keys     []string
+		wantKeys []string
+		wantHelp string
+	}{
+		{keys: []string{"x"}, wantKeys: []string{"x"}, wantHelp: "x"},
+		{keys: []string{"g  g"}, wantKeys: []string{"g g"}, wantHelp: "gg"},
+		{keys: []string{"] c", "ctrl+x n"}, wantKeys: []string{"] c", "ctrl+x n"}, wantHelp: "]c/ctrl+x n"},
+	}
+	for _, tt := range tests {
+		b := key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "do"))
+		if err := rebind(&b, tt.keys); err != nil {
+			t.Fatalf("rebind(%q) = %v", tt.keys, err)
+		}
+		if !reflect.DeepEqual(b.Keys(), tt.wantKeys) || b.Help().Key != tt.wantHelp || b.Help().Desc != "do" {
+			t.Errorf("rebind(%q) = %q %q, want %q %q", tt.keys, b.Keys(), b.Help(), tt.wantKeys, tt.wantHelp)
+		}
+	}
+}
+
+func TestChord(t *testing.T) {
+	bindings := []key.Binding{
+		key.NewBinding(key.WithKeys("j")),
+		key.NewBinding(key.WithKeys("g g")),
+		key.NewBinding(key.WithKeys("] c")),
+		key.NewBinding(key.WithKeys("space")),
+		key.NewBinding(key.WithKeys("z z"), key.WithDisabled()),
+	}
+	tests := []struct {
+		name    string
+		presses []tea.KeyMsg
+		want    []Sequence
+	}{
+		{
+			name:    "single key",
+			presses: []tea.KeyMsg{runes("j")},
+			want:    []Sequence{"j"},
+		},
+		{
+			name:    "sequence",
+			presses: []tea.KeyMsg{runes("g"), runes("g")},
+			want:    []Sequence{"g g"},
+		},
+		{
+			name:    "a key that doesn't complete the sequence is replayed",
+			presses: []tea.KeyMsg{runes("g"), runes("j")},
+			want:    []Sequence{"j"},
+		},
+		{
+			name:    "the replayed key may start a sequence",
+			presses: []tea.KeyMsg{runes("g"), runes("]"), runes("c")},
+			want:    []Sequence{"] c"},
+		},
+		{
+			name:    "unbound keys are passed on",
+			presses: []tea.KeyMsg{runes("x"), runes("g"), runes("x")},
+			want:    []Sequence{"x", "x"},
+		},
+		{
+			name:    "space",
+			presses: []tea.KeyMsg{{Type: tea.KeySpace, Runes: []rune(" ")}},
+			want:    []Sequence{"space"},
+		},
+		{
+			name:    "disabled bindings aren't waited for",
+			presses: []tea.KeyMsg{runes("z"), runes("z")},
+			want:    []Sequence{"z", "z"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chord Chord
+			got := make([]Sequence, 0)
+			for _, press := range tt.presses {
+				if seq, ok := chord.Feed(press, bindings); ok {
+					got = append(got, seq)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Feed() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
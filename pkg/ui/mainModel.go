@@ -2,7 +2,9 @@ package ui
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
 	"github.com/charmbracelet/bubbles/help"
@@ -13,12 +15,18 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 
-	"github.com/dlvhdr/diffnav/pkg/constants"
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/filefilter"
 	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/generated"
+	"github.com/dlvhdr/diffnav/pkg/prefs"
+	"github.com/dlvhdr/diffnav/pkg/review"
 	"github.com/dlvhdr/diffnav/pkg/ui/common"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
 	"github.com/dlvhdr/diffnav/pkg/ui/panes/diffviewer"
 	"github.com/dlvhdr/diffnav/pkg/ui/panes/filetree"
 	"github.com/dlvhdr/diffnav/pkg/utils"
+	"github.com/dlvhdr/diffnav/pkg/viewed"
 )
 
 const (
@@ -28,9 +36,24 @@ const (
 )
 
 type mainModel struct {
-	input             string
-	files             []*gitdiff.File
-	cursor            int
+	source  Source
+	loading chan tea.Msg
+	loaded  bool
+	commits []*commit
+	// commit is the index of the selected commit, files are its files.
+	commit int
+	files  []*gitdiff.File
+	// rebuilt is when the tree was last rebuilt with the files loaded so far,
+	// rebuildQueued is set while a rebuild waits for its turn.
+	rebuilt       time.Time
+	rebuildQueued bool
+	// shown are the files kept by the filter, the ones in the tree.
+	shown             []*gitdiff.File
+	current           *gitdiff.File
+	viewed            *viewed.Store
+	skipViewed        bool
+	review            *review.Review
+	generated         *generated.Rules
 	fileTree          filetree.Model
 	diffViewer        diffviewer.Model
 	width             int
@@ -41,21 +64,63 @@ type mainModel struct {
 	resultsVp         viewport.Model
 	resultsCursor     int
 	searching         bool
-	filtered          []string
+	filtered          []searchResult
+	chord             keys.Chord
+	showingHelp       bool
+	helpVp            viewport.Model
+	find              finder
+	filterBar         filterBar
+	// showMessage expands the commit message above the diff.
+	showMessage bool
+	// whitespaceOnly counts the files of the tree that only changed
+	// whitespace.
+	whitespaceOnly int
+	// previewed is the file shown in the diff while moving through search
+	// results, nil when it's the selected file.
+	previewed *gitdiff.File
 }
 
-func New(input string) mainModel {
-	m := mainModel{input: input, isShowingFileTree: true}
-	m.fileTree = filetree.New()
-	m.diffViewer = diffviewer.New()
+// Options configure the UI.
+type Options struct {
+	Renderer diffviewer.Renderer
+	// Viewed persists the files marked as viewed.
+	Viewed *viewed.Store
+	// SkipViewed passes over viewed files when moving between files.
+	SkipViewed bool
+	// Review collects the comments left on the diff.
+	Review *review.Review
+	// Filter narrows the files shown in the tree.
+	Filter filefilter.Filter
+	// Generated collapses the diff of generated files.
+	Generated *generated.Rules
+	// View lays out the diffs, the one of the config when empty.
+	View config.ViewMode
+}
+
+func New(source Source, opts Options) mainModel {
+	m := mainModel{
+		source:            source,
+		loading:           make(chan tea.Msg),
+		isShowingFileTree: true,
+		viewed:            opts.Viewed,
+		skipViewed:        opts.SkipViewed,
+		review:            opts.Review,
+		generated:         opts.Generated,
+	}
+	m.fileTree = filetree.New().SetViewed(m.viewed.IsViewed).SetComments(m.commentCount).SetGenerated(m.isGenerated)
+	m.diffViewer = diffviewer.New(opts.Renderer, opts.Review).SetCollapsed(m.collapsedReason)
+	if opts.View != "" {
+		m.diffViewer, _ = m.diffViewer.SetView(opts.View)
+	}
 
+	theme := config.Get().Theme
 	m.help = help.New()
 	helpSt := lipgloss.NewStyle()
 	m.help.ShortSeparator = " · "
 	m.help.Styles.ShortKey = helpSt
 	m.help.Styles.ShortDesc = helpSt
 	m.help.Styles.ShortSeparator = helpSt
-	m.help.Styles.ShortKey = helpSt.Foreground(lipgloss.Color("254"))
+	m.help.Styles.ShortKey = helpSt.Foreground(lipgloss.Color(theme.Text))
 	m.help.Styles.ShortDesc = helpSt
 	m.help.Styles.ShortSeparator = helpSt
 	m.help.Styles.Ellipsis = helpSt
@@ -64,12 +129,14 @@ func New(input string) mainModel {
 	m.search.ShowSuggestions = true
 	m.search.KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("tab"))
 	m.search.Prompt = " "
-	m.search.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	m.search.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
 	m.search.Placeholder = "Filter files 󰬛 "
-	m.search.PlaceholderStyle = lipgloss.NewStyle().MaxWidth(lipgloss.Width(m.search.Placeholder)).Foreground(lipgloss.Color("8"))
-	m.search.Width = constants.OpenFileTreeWidth - 5
+	m.search.PlaceholderStyle = lipgloss.NewStyle().MaxWidth(lipgloss.Width(m.search.Placeholder)).Foreground(lipgloss.Color(theme.Muted))
+	m.search.Width = config.Get().Sidebar.Width - 5
 
 	m.resultsVp = viewport.Model{}
+	m.find = newFinder()
+	m.filterBar = newFilterBar(opts.Filter)
 
 	return m
 }
@@ -82,62 +149,169 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
+	// files, and errors reading them, keep streaming in while searching
+	switch msg := msg.(type) {
+	case fileTreeMsg:
+		cmd = m.addFiles(msg.header, msg.files)
+		cmds = append(cmds, cmd, m.waitForFiles)
+
+	case rebuildMsg:
+		m.rebuildQueued = false
+		cmds = append(cmds, m.rebuild())
+
+	case fileTreeLoadedMsg:
+		m.loaded = true
+		if !m.hasFiles() {
+			return m, tea.Quit
+		}
+		// the last files may be waiting for a rebuild, and an empty commit
+		// tells it has no files once loaded
+		cmds = append(cmds, m.rebuild())
+
+	case common.ErrMsg:
+		fmt.Printf("Error: %v\n", msg.Err)
+		log.Fatal(msg.Err)
+	}
+
 	if !m.searching {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "ctrl+c", "q":
+			km := keys.Get()
+			// the diff viewer handles every key while lines are selected
+			if m.diffViewer.IsSelecting() {
+				if key.Matches(keys.Press(msg), km.ForceQuit) {
+					return m, tea.Quit
+				}
+				m.diffViewer, cmd = m.diffViewer.Update(msg)
+				cmds = append(cmds, cmd)
+				break
+			}
+			if m.find.typing {
+				var fCmds []tea.Cmd
+				m, fCmds = m.findUpdate(msg)
+				cmds = append(cmds, fCmds...)
+				break
+			}
+			if m.filterBar.editing {
+				var fCmds []tea.Cmd
+				m, fCmds = m.filterUpdate(msg)
+				cmds = append(cmds, fCmds...)
+				break
+			}
+			if m.showingHelp {
+				seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextHelp))
+				if !ok {
+					break
+				}
+				switch {
+				case key.Matches(seq, km.ForceQuit):
+					return m, tea.Quit
+				case key.Matches(seq, km.CloseHelp):
+					m.showingHelp = false
+				case key.Matches(seq, km.HelpDown):
+					m.helpVp.LineDown(1)
+				case key.Matches(seq, km.HelpUp):
+					m.helpVp.LineUp(1)
+				}
+				break
+			}
+			seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextMain))
+			if !ok {
+				break
+			}
+			switch {
+			case key.Matches(seq, km.Quit, km.ForceQuit):
 				return m, tea.Quit
-			case "t":
+			case key.Matches(seq, km.Help):
+				m.openHelp()
+			case key.Matches(seq, km.Search):
 				m.searching = true
 				m.search.Width = m.sidebarWidth() - 5
 				m.search.SetValue("")
-				m.resultsCursor = 0
-				m.filtered = make([]string, 0)
-
-				m.resultsVp.Width = constants.SearchingFileTreeWidth
+				m.resultsVp.Width = config.Get().Sidebar.SearchWidth
 				m.resultsVp.Height = m.height - footerHeight - headerHeight - searchHeight
-				m.resultsVp.SetContent(m.resultsView())
+				m.filterResults()
+				m.resultsCursor = max(0, slices.IndexFunc(m.filtered, func(r searchResult) bool { return r.file == m.current }))
+				m.scrollResults()
 
-				dfCmd := m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight)
+				dfCmd := m.resizeDiff()
 				cmds = append(cmds, dfCmd, m.search.Focus())
-			case "e":
+			case key.Matches(seq, km.FindInFile, km.FindInAll):
+				if m.current != nil {
+					cmds = append(cmds, m.openFind(key.Matches(seq, km.FindInAll)))
+				}
+			case key.Matches(seq, km.NextMatch):
+				cmds = append(cmds, m.stepMatch(1))
+			case key.Matches(seq, km.PrevMatch):
+				cmds = append(cmds, m.stepMatch(-1))
+			case key.Matches(seq, km.ClearFind):
+				cmds = append(cmds, m.clearFind())
+			case key.Matches(seq, km.ToggleFileTree):
 				m.isShowingFileTree = !m.isShowingFileTree
-				dfCmd := m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight)
+				dfCmd := m.resizeDiff()
 				cmds = append(cmds, dfCmd)
-			case "up", "k", "ctrl+p":
-				if m.cursor > 0 {
-					cmd = m.setCursor(m.cursor - 1)
-					cmds = append(cmds, cmd)
-				}
-			case "down", "j", "ctrl+n":
-				if m.cursor < len(m.files)-1 {
-					cmd = m.setCursor(m.cursor + 1)
-					cmds = append(cmds, cmd)
-				}
+			case key.Matches(seq, km.Up):
+				m.fileTree = m.fileTree.CursorUp(m.skip())
+				cmds = append(cmds, m.showSelectedFile())
+			case key.Matches(seq, km.Down):
+				m.fileTree = m.fileTree.CursorDown(m.skip())
+				cmds = append(cmds, m.showSelectedFile())
+			case key.Matches(seq, km.Collapse):
+				m.fileTree = m.fileTree.Collapse()
+				cmds = append(cmds, m.showSelectedFile())
+			case key.Matches(seq, km.Expand):
+				m.fileTree = m.fileTree.Expand()
+			case key.Matches(seq, km.ToggleDir):
+				m.fileTree = m.fileTree.Toggle()
+			case key.Matches(seq, km.ToggleViewed):
+				m.toggleViewed()
+			case key.Matches(seq, km.FilterFiles):
+				cmds = append(cmds, m.openFilter())
+			case key.Matches(seq, km.NextCommit):
+				cmds = append(cmds, m.stepCommit(1))
+			case key.Matches(seq, km.PrevCommit):
+				cmds = append(cmds, m.stepCommit(-1))
+			case key.Matches(seq, km.ToggleMessage):
+				cmds = append(cmds, m.toggleMessage())
+			default:
+				m.diffViewer, cmd = m.diffViewer.Update(seq)
+				cmds = append(cmds, cmd)
 			}
 
 		case tea.WindowSizeMsg:
 			m.help.Width = msg.Width
 			m.width = msg.Width
 			m.height = msg.Height
-			dfCmd := m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight)
+			dfCmd := m.resizeDiff()
 			cmds = append(cmds, dfCmd)
-			ftCmd := m.fileTree.SetSize(m.sidebarWidth(), m.height-footerHeight-headerHeight-searchHeight)
+			ftCmd := m.resizeTree()
 			cmds = append(cmds, ftCmd)
+			m.resizeHelp()
 
-		case fileTreeMsg:
-			m.files = msg.files
-			if len(m.files) == 0 {
-				return m, tea.Quit
+		case diffviewer.NextFileMsg:
+			if next := m.fileTree.FileAfter(m.current, 1); next != nil {
+				cmd = m.selectFile(next)
+				m.diffViewer = m.diffViewer.JumpToFirstHunk()
+				cmds = append(cmds, cmd)
 			}
-			m.fileTree = m.fileTree.SetFiles(m.files)
-			cmd = m.setCursor(0)
-			cmds = append(cmds, cmd)
 
-		case common.ErrMsg:
-			fmt.Printf("Error: %v\n", msg.Err)
-			log.Fatal(msg.Err)
+		case diffviewer.PrevFileMsg:
+			if prev := m.fileTree.FileAfter(m.current, -1); prev != nil {
+				cmd = m.selectFile(prev)
+				m.diffViewer = m.diffViewer.JumpToLastHunk()
+				cmds = append(cmds, cmd)
+			}
+
+		case diffviewer.CommentsChangedMsg:
+			m.fileTree = m.fileTree.SetComments(m.commentCount)
+
+		case diffviewer.WhitespaceChangedMsg:
+			cmds = append(cmds, m.applyFilter())
+
+		case diffviewer.ViewChangedMsg:
+			if err := prefs.Update(func(p *prefs.Prefs) { p.View = msg.View }); err != nil {
+				log.Error("failed saving the view mode", "err", err)
+			}
 		}
 	} else {
 		var sCmds []tea.Cmd
@@ -145,155 +319,228 @@ func (m mainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, sCmds...)
 	}
 
-	m.diffViewer, cmd = m.diffViewer.Update(msg)
-	cmds = append(cmds, cmd)
-
-	m.fileTree, cmd = m.fileTree.Update(msg)
-	cmds = append(cmds, cmd)
+	// keys were dispatched above
+	if _, ok := msg.(tea.KeyMsg); !ok {
+		m.diffViewer, cmd = m.diffViewer.Update(msg)
+		cmds = append(cmds, cmd)
+		m.fileTree, cmd = m.fileTree.Update(msg)
+		cmds = append(cmds, cmd)
+		if m.find.typing {
+			m.find.input, cmd = m.find.input.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+		if m.filterBar.editing {
+			m.filterBar.input, cmd = m.filterBar.input.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+	}
 
 	return m, tea.Batch(cmds...)
 }
 
 func (m mainModel) searchUpdate(msg tea.Msg) (mainModel, []tea.Cmd) {
-	var cmd tea.Cmd
 	var cmds []tea.Cmd
 	if m.search.Focused() {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
-			switch msg.String() {
-			case "esc":
+			km := keys.Get()
+			seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextSearch))
+			if !ok {
+				return m, cmds
+			}
+			switch {
+			case key.Matches(seq, km.SearchCancel):
 				m.stopSearch()
-				dfCmd := m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight)
-				cmds = append(cmds, dfCmd)
-			case "ctrl+c":
+				dfCmd := m.resizeDiff()
+				cmds = append(cmds, dfCmd, m.cancelPreview())
+				return m, cmds
+			case key.Matches(seq, km.ForceQuit):
 				return m, []tea.Cmd{tea.Quit}
-			case "enter":
+			case key.Matches(seq, km.SearchSelect):
 				m.stopSearch()
-				dfCmd := m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight)
+				dfCmd := m.resizeDiff()
 				cmds = append(cmds, dfCmd)
 
-				selected := m.filtered[m.resultsCursor]
-				for i, f := range m.files {
-					if filenode.GetFileName(f) == selected {
-						m.cursor = i
-						m.diffViewer, cmd = m.diffViewer.SetFilePatch(f)
-						cmds = append(cmds, cmd)
-						break
-					}
+				if m.resultsCursor < len(m.filtered) {
+					cmds = append(cmds, m.selectResult(m.filtered[m.resultsCursor].file))
+				} else {
+					cmds = append(cmds, m.cancelPreview())
 				}
+				return m, cmds
 
-			case "ctrl+n", "down":
-				m.resultsCursor = min(len(m.files)-1, m.resultsCursor+1)
-				m.resultsVp.LineDown(1)
-			case "ctrl+p", "up":
+			case key.Matches(seq, km.SearchDown):
+				m.resultsCursor = min(len(m.filtered)-1, m.resultsCursor+1)
+			case key.Matches(seq, km.SearchUp):
 				m.resultsCursor = max(0, m.resultsCursor-1)
-				m.resultsVp.LineUp(1)
-			default:
-				m.resultsCursor = 0
 			}
 		}
+		query := m.search.Value()
 		s, sc := m.search.Update(msg)
 		cmds = append(cmds, sc)
 		m.search = s
-		filtered := make([]string, 0)
-		for _, f := range m.files {
-			if strings.Contains(strings.ToLower(filenode.GetFileName(f)), strings.ToLower(m.search.Value())) {
-				filtered = append(filtered, filenode.GetFileName(f))
-			}
+		if m.search.Value() != query {
+			m.filterResults()
+			m.resultsCursor = 0
 		}
-		m.filtered = filtered
-		m.resultsVp.SetContent(m.resultsView())
+		m.scrollResults()
+		cmds = append(cmds, m.previewResult())
 	}
 
 	return m, cmds
 }
 
 func (m mainModel) View() string {
+	theme := config.Get().Theme
 	header := lipgloss.NewStyle().Width(m.width).
 		Border(lipgloss.NormalBorder(), false, false, true, false).
-		BorderForeground(lipgloss.Color("8")).
-		Foreground(lipgloss.Color("6")).
-		Bold(true).
-		Render("DIFFNAV")
+		BorderForeground(lipgloss.Color(theme.Border)).
+		Render(lipgloss.JoinHorizontal(lipgloss.Top, lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent)).Bold(true).Render("DIFFNAV"), m.progressView(), m.filterView(), m.whitespaceView(), m.loadingView()))
 	footer := m.footerView()
 
 	sidebar := ""
-	if m.isShowingFileTree {
+	if m.isShowingFileTree || m.searching || m.find.typing || m.filterBar.editing {
+		input := m.search.View()
+		if m.find.typing {
+			input = m.find.input.View()
+		} else if m.filterBar.editing {
+			input = m.filterBar.input.View()
+		}
 		search := lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("8")).
+			BorderForeground(lipgloss.Color(theme.Border)).
 			MaxHeight(3).
 			Width(m.sidebarWidth() - 2).
-			Render(m.search.View())
+			Render(input)
 
 		content := ""
 		width := m.sidebarWidth()
 		if m.searching {
 			content = m.resultsVp.View()
+		} else if m.find.typing {
+			content = m.findResultsView()
+		} else if m.filterBar.editing {
+			content = m.filterHintView() + "\n" + m.treeView()
 		} else {
-			content = m.fileTree.View()
+			content = m.treeView()
+		}
+		if commits := m.commitsView(); commits != "" && !m.searching && !m.find.typing {
+			content = commits + "\n" + content
 		}
 
 		content = lipgloss.NewStyle().
 			Width(width).
-			Height(m.height - footerHeight - headerHeight).Render(lipgloss.JoinVertical(lipgloss.Left, search, content))
+			Height(m.height - footerHeight - headerHeight).
+			MaxHeight(m.height - footerHeight - headerHeight).Render(lipgloss.JoinVertical(lipgloss.Left, search, content))
 
 		sidebar = lipgloss.NewStyle().
 			Width(width).
 			Border(lipgloss.NormalBorder(), false, true, false, false).
-			BorderForeground(lipgloss.Color("8")).Render(content)
+			BorderForeground(lipgloss.Color(theme.Border)).Render(content)
+	}
+	diff := m.diffViewer.View()
+	if message := m.messageView(); message != "" {
+		diff = lipgloss.JoinVertical(lipgloss.Left, message, diff)
+	}
+	dv := lipgloss.NewStyle().MaxHeight(m.height - footerHeight - headerHeight).Width(m.width - m.sidebarWidth()).Render(diff)
+	body := lipgloss.JoinHorizontal(lipgloss.Top, sidebar, dv)
+	if m.showingHelp {
+		body = m.helpView()
 	}
-	dv := lipgloss.NewStyle().MaxHeight(m.height - footerHeight - headerHeight).Width(m.width - m.sidebarWidth()).Render(m.diffViewer.View())
 	return lipgloss.JoinVertical(lipgloss.Left,
 		header,
-		lipgloss.JoinHorizontal(lipgloss.Top, sidebar, dv),
+		body,
 		footer,
 	)
 }
 
 type fileTreeMsg struct {
-	files []*gitdiff.File
+	header *gitdiff.PatchHeader
+	files  []*gitdiff.File
 }
 
+type fileTreeLoadedMsg struct{}
+
+// fetchFileTree starts streaming files from the source and waits for the first batch.
 func (m mainModel) fetchFileTree() tea.Msg {
-	// TODO: handle error
-	files, _, err := gitdiff.Parse(strings.NewReader(m.input + "\n"))
-	if err != nil {
-		return common.ErrMsg{Err: err}
+	go func() {
+		err := m.source(func(header *gitdiff.PatchHeader, files []*gitdiff.File) {
+			m.loading <- fileTreeMsg{header: header, files: files}
+		})
+		if err != nil {
+			m.loading <- common.ErrMsg{Err: err}
+		}
+		close(m.loading)
+	}()
+	return m.waitForFiles()
+}
+
+func (m mainModel) waitForFiles() tea.Msg {
+	msg, ok := <-m.loading
+	if !ok {
+		return fileTreeLoadedMsg{}
+	}
+	return msg
+}
+
+func (m mainModel) loadingView() string {
+	if m.loaded {
+		return ""
+	}
+	count := 0
+	for _, c := range m.commits {
+		count += len(c.files)
 	}
-	sortFiles(files)
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted)).Render(fmt.Sprintf("  loading %d files…", count))
+}
 
-	return fileTreeMsg{files: files}
+func (m mainModel) progressView() string {
+	if len(m.shown) == 0 {
+		return ""
+	}
+	count := 0
+	for _, file := range m.shown {
+		if m.viewed.IsViewed(file) {
+			count++
+		}
+	}
+	theme := config.Get().Theme
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	if count == len(m.shown) {
+		style = style.Foreground(lipgloss.Color(theme.Viewed))
+	}
+	return style.Render(fmt.Sprintf("  %d/%d viewed", count, len(m.shown)))
 }
 
 func (m mainModel) footerView() string {
 	return lipgloss.NewStyle().
 		Width(m.width).
 		Border(lipgloss.NormalBorder(), true, false, false, false).
-		BorderForeground(lipgloss.Color("8")).
+		BorderForeground(lipgloss.Color(config.Get().Theme.Border)).
 		Height(1).
-		Render(m.help.ShortHelpView(getKeys()))
+		Render(m.help.ShortHelpView(keys.Get().ShortHelp()))
 
 }
 
 func (m mainModel) resultsView() string {
+	theme := config.Get().Theme
 	sb := strings.Builder{}
-	for i, f := range m.filtered {
-		fName := utils.TruncateString(" "+f, constants.SearchingFileTreeWidth-2)
+	for i, r := range m.filtered {
+		base := lipgloss.NewStyle()
 		if i == m.resultsCursor {
-			sb.WriteString(lipgloss.NewStyle().Background(lipgloss.Color("#1b1b33")).Bold(true).Render(fName) + "\n")
-		} else {
-			sb.WriteString(fName + "\n")
+			base = base.Background(lipgloss.Color(theme.SelectedBackground)).Bold(true)
 		}
+		matched := base.Foreground(lipgloss.Color(theme.Accent)).Bold(true).Underline(true)
+		name := base.Render(" ") + highlightMatches(r.name, r.positions, base, matched)
+		sb.WriteString(utils.TruncateString(name, config.Get().Sidebar.SearchWidth-2) + "\n")
 	}
 	return sb.String()
 }
 
 func (m mainModel) sidebarWidth() int {
-	if m.searching {
-		return constants.SearchingFileTreeWidth
+	if m.searching || m.find.typing || m.filterBar.editing {
+		return config.Get().Sidebar.SearchWidth
 	} else if m.isShowingFileTree {
-		return constants.OpenFileTreeWidth
+		return config.Get().Sidebar.Width
 	} else {
 		return 0
 	}
@@ -306,10 +553,75 @@ func (m *mainModel) stopSearch() {
 	m.search.Width = m.sidebarWidth() - 5
 }
 
-func (m *mainModel) setCursor(cursor int) tea.Cmd {
+// toggleViewed marks the file under the cursor as viewed, or all the files of
+// the directory under it. When some of them are already viewed, they're all
+// marked as viewed first.
+func (m *mainModel) toggleViewed() {
+	files := m.fileTree.SelectedFiles()
+	if len(files) == 0 {
+		return
+	}
+	isViewed := true
+	for _, file := range files {
+		isViewed = isViewed && m.viewed.IsViewed(file)
+	}
+	for _, file := range files {
+		m.viewed.SetViewed(file, !isViewed)
+	}
+	m.fileTree = m.fileTree.SetViewed(m.viewed.IsViewed)
+	if err := m.viewed.Save(); err != nil {
+		log.Error("failed saving viewed files", "err", err)
+	}
+}
+
+func (m mainModel) commentCount(file *gitdiff.File) int {
+	return len(m.review.ForFile(filenode.GetFileName(file)))
+}
+
+// collapsedReason tells why the diff of file is collapsed by default.
+func (m mainModel) collapsedReason(file *gitdiff.File) generated.Reason {
+	return m.generated.Reason(filenode.GetFileName(file))
+}
+
+func (m mainModel) isGenerated(file *gitdiff.File) bool {
+	return m.collapsedReason(file) != ""
+}
+
+// skip returns the files to pass over when moving between files.
+func (m mainModel) skip() func(*gitdiff.File) bool {
+	if !m.skipViewed {
+		return nil
+	}
+	return m.viewed.IsViewed
+}
+
+// selectFile moves the tree cursor to file and shows its diff.
+func (m *mainModel) selectFile(file *gitdiff.File) tea.Cmd {
+	m.fileTree = m.fileTree.SelectFile(file)
+	return m.showSelectedFile()
+}
+
+// showSelectedFile shows the diff of the file under the tree cursor. The diff
+// is left as is while the cursor is on a directory.
+func (m *mainModel) showSelectedFile() tea.Cmd {
+	file := m.fileTree.SelectedFile()
+	if file == nil || file == m.current {
+		return nil
+	}
 	var cmd tea.Cmd
-	m.cursor = cursor
-	m.diffViewer, cmd = m.diffViewer.SetFilePatch(m.files[m.cursor])
-	m.fileTree = m.fileTree.SetCursor(m.cursor)
-	return cmd
+	m.current = file
+	m.diffViewer, cmd = m.diffViewer.SetFilePatch(file)
+	return tea.Batch(cmd, m.prefetchNeighbors())
+}
+
+// prefetchNeighbors renders the files around the current one ahead of time.
+func (m mainModel) prefetchNeighbors() tea.Cmd {
+	neighbors := make([]*gitdiff.File, 0, 2)
+	if next := m.fileTree.FileAfter(m.current, 1); next != nil {
+		neighbors = append(neighbors, next)
+	}
+	if prev := m.fileTree.FileAfter(m.current, -1); prev != nil {
+		neighbors = append(neighbors, prev)
+	}
+	return m.diffViewer.Prefetch(neighbors...)
 }
@@ -0,0 +1,126 @@
+package ui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/trailer"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+// messageIndent lines up the message with the commit hash above it.
+const messageIndent = "   "
+
+// resizeDiff fits the diff in the space left by the sidebar and the commit
+// message.
+func (m *mainModel) resizeDiff() tea.Cmd {
+	return m.diffViewer.SetSize(m.width-m.sidebarWidth(), m.height-footerHeight-headerHeight-m.messageHeight())
+}
+
+// toggleMessage shows the whole commit message above the diff, or only its title.
+func (m *mainModel) toggleMessage() tea.Cmd {
+	if m.header() == nil {
+		return nil
+	}
+	m.showMessage = !m.showMessage
+	return m.resizeDiff()
+}
+
+func (m mainModel) messageHeight() int {
+	if m.header() == nil {
+		return 0
+	}
+	return lipgloss.Height(m.messageView())
+}
+
+// messageView shows the commit of the diff above it, a plain diff has none.
+// Collapsed it's the title on a single line, expanded it's the author, the
+// committer, the message and its trailers, up to half the height of the diff.
+func (m mainModel) messageView() string {
+	header := m.header()
+	if header == nil {
+		return ""
+	}
+	theme := config.Get().Theme
+	width := m.width - m.sidebarWidth()
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	accent := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Accent))
+	bold := lipgloss.NewStyle().Bold(true)
+	c := m.commits[m.commit]
+
+	var lines []string
+	if !m.showMessage {
+		line := " ▸ "
+		if header.SHA != "" {
+			line += accent.Render(header.SHA[:min(7, len(header.SHA))]) + " "
+		}
+		line += bold.Render(c.title())
+		if byline := c.byline(); byline != "" {
+			line += muted.Render(" · " + byline)
+		}
+		if key := keys.Get().ToggleMessage.Help().Key; key != "" {
+			line += muted.Render(" (" + key + ")")
+		}
+		lines = append(lines, line)
+	} else {
+		sha := header.SHA
+		if sha == "" {
+			sha = "commit"
+		}
+		lines = append(lines, " ▾ "+accent.Render(sha))
+		if header.Author != nil {
+			lines = append(lines, identityLine("Author:    ", header.Author, header.AuthorDate))
+		}
+		if header.Committer != nil {
+			lines = append(lines, identityLine("Committer: ", header.Committer, header.CommitterDate))
+		}
+		title := c.title()
+		if header.SubjectPrefix != "" {
+			title = strings.TrimSpace(header.SubjectPrefix) + " " + title
+		}
+		lines = append(lines, "", messageIndent+bold.Render(title))
+
+		message, trailers := trailer.Split(header.Body)
+		if message != "" {
+			lines = append(lines, "")
+			for _, line := range strings.Split(message, "\n") {
+				lines = append(lines, messageIndent+line)
+			}
+		}
+		if len(trailers) > 0 {
+			lines = append(lines, "")
+			for _, t := range trailers {
+				lines = append(lines, messageIndent+accent.Render(t.Token+":")+" "+t.Value)
+			}
+		}
+
+		if limit := max(2, (m.height-footerHeight-headerHeight)/2); len(lines) > limit {
+			lines = append(lines[:limit-1], muted.Render(messageIndent+"…"))
+		}
+	}
+
+	for i, line := range lines {
+		lines[i] = utils.TruncateString(strings.ReplaceAll(line, "\t", "    "), width)
+	}
+	return lipgloss.NewStyle().
+		Width(width).
+		Border(lipgloss.NormalBorder(), false, false, true, false).
+		BorderForeground(lipgloss.Color(theme.Border)).
+		Render(strings.Join(lines, "\n"))
+}
+
+// identityLine tells who authored or committed the commit, and when.
+func identityLine(label string, identity *gitdiff.PatchIdentity, date time.Time) string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted))
+	line := messageIndent + muted.Render(label) + identity.String()
+	if !date.IsZero() {
+		line += muted.Render(" · " + date.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	}
+	return line
+}
@@ -0,0 +1,300 @@
+package diffviewer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/diffsearch"
+	"github.com/dlvhdr/diffnav/pkg/worddiff"
+)
+
+const tabWidth = 4
+
+var (
+	addedLineStyle   lipgloss.Style
+	deletedLineStyle lipgloss.Style
+	addedEmphStyle   lipgloss.Style
+	deletedEmphStyle lipgloss.Style
+	contextLineStyle lipgloss.Style
+	addedSignStyle   lipgloss.Style
+	deletedSignStyle lipgloss.Style
+	lineNumberStyle  lipgloss.Style
+	hunkHeaderStyle  lipgloss.Style
+	placeholderStyle lipgloss.Style
+	searchMatchStyle lipgloss.Style
+)
+
+// applyTheme sets up the styles of the diff from the configured theme.
+func applyTheme(theme config.Theme) {
+	addedLineStyle = lipgloss.NewStyle().Background(lipgloss.Color(theme.AddedLineBackground))
+	deletedLineStyle = lipgloss.NewStyle().Background(lipgloss.Color(theme.DeletedLineBackground))
+	addedEmphStyle = lipgloss.NewStyle().Background(lipgloss.Color(theme.AddedEmphBackground))
+	deletedEmphStyle = lipgloss.NewStyle().Background(lipgloss.Color(theme.DeletedEmphBackground))
+	contextLineStyle = lipgloss.NewStyle()
+	addedSignStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Added))
+	deletedSignStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Deleted))
+	lineNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+	hunkHeaderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.HunkHeader)).Background(lipgloss.Color(theme.HunkHeaderBackground))
+	placeholderStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Italic(true)
+	searchMatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.SearchMatch)).Background(lipgloss.Color(theme.SearchMatchBackground))
+
+	selectionMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Selection))
+	commentMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Comment))
+	matchMarkerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.SearchMatchBackground))
+	editorStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(theme.Border))
+	editorTitleStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted))
+}
+
+// renderBuiltin draws the file's text fragments without any external tool,
+// highlighting the matches of search. The first hunk header is left out as it's
+// pinned above the viewport.
+func renderBuiltin(file *gitdiff.File, width int, sideBySide bool, search diffsearch.Query) rendered {
+	if len(file.TextFragments) == 0 {
+		return rendered{text: placeholderStyle.Render(" No content changes")}
+	}
+
+	numWidth := lineNumberWidth(file)
+	hl := newHighlighter(file)
+	rows := make([]string, 0)
+	lines := make([]lineRef, 0)
+	hunks := make([]int, 0, len(file.TextFragments))
+	for i, frag := range file.TextFragments {
+		segments := hl.highlightFragment(frag)
+		for _, pair := range worddiff.PairLines(frag.Lines, lineText) {
+			segments[pair.Old] = emphasize(segments[pair.Old], pair.OldRanges)
+			segments[pair.New] = emphasize(segments[pair.New], pair.NewRanges)
+		}
+		for j, line := range frag.Lines {
+			segments[j] = markMatches(segments[j], search.Find(line.Op, lineText(line)))
+		}
+		if i > 0 {
+			rows = append(rows, renderHunkHeader(frag, width))
+			lines = append(lines, lineRef{})
+		}
+		hunks = append(hunks, len(rows))
+		var fragRows []string
+		var fragLines []lineRef
+		if sideBySide {
+			fragRows, fragLines = renderSideBySideFragment(frag, segments, width, numWidth)
+		} else {
+			fragRows, fragLines = renderUnifiedFragment(frag, segments, width, numWidth)
+		}
+		rows = append(rows, fragRows...)
+		lines = append(lines, fragLines...)
+	}
+	return rendered{text: strings.Join(rows, "\n"), hunks: hunks, lines: lines}
+}
+
+func renderHunkHeader(frag *gitdiff.TextFragment, width int) string {
+	header := strings.TrimSuffix(frag.Header(), "\n")
+	return hunkHeaderStyle.Width(width).Render(ansi.Truncate(" "+header, width, "…"))
+}
+
+func renderUnifiedFragment(frag *gitdiff.TextFragment, segments [][]segment, width int, numWidth int) ([]string, []lineRef) {
+	rows := make([]string, 0, len(frag.Lines))
+	lines := make([]lineRef, 0, len(frag.Lines))
+	oldNum, newNum := frag.OldPosition, frag.NewPosition
+	for i, line := range frag.Lines {
+		old, new := "", ""
+		ref := lineRef{}
+		switch line.Op {
+		case gitdiff.OpContext:
+			old, new = strconv.FormatInt(oldNum, 10), strconv.FormatInt(newNum, 10)
+			ref = lineRef{old: oldNum, new: newNum}
+			oldNum++
+			newNum++
+		case gitdiff.OpDelete:
+			old = strconv.FormatInt(oldNum, 10)
+			ref.old = oldNum
+			oldNum++
+		case gitdiff.OpAdd:
+			new = strconv.FormatInt(newNum, 10)
+			ref.new = newNum
+			newNum++
+		}
+		gutter := fmt.Sprintf("%*s %*s ", numWidth, old, numWidth, new)
+		rows = append(rows, renderCell(gutter, line.Op, segments[i], width))
+		lines = append(lines, ref)
+	}
+	return rows, lines
+}
+
+func renderSideBySideFragment(frag *gitdiff.TextFragment, segments [][]segment, width int, numWidth int) ([]string, []lineRef) {
+	leftWidth := (width - 1) / 2
+	rightWidth := width - 1 - leftWidth
+	separator := lineNumberStyle.Render("│")
+
+	rows := make([]string, 0, len(frag.Lines))
+	lines := make([]lineRef, 0, len(frag.Lines))
+	oldNum, newNum := frag.OldPosition, frag.NewPosition
+	for _, pair := range pairLines(frag.Lines) {
+		ref := lineRef{}
+		left := strings.Repeat(" ", leftWidth)
+		if pair.old != -1 {
+			gutter := fmt.Sprintf("%*d ", numWidth, oldNum)
+			left = renderCell(gutter, frag.Lines[pair.old].Op, segments[pair.old], leftWidth)
+			ref.old = oldNum
+			oldNum++
+		}
+		right := strings.Repeat(" ", rightWidth)
+		if pair.new != -1 {
+			gutter := fmt.Sprintf("%*d ", numWidth, newNum)
+			right = renderCell(gutter, frag.Lines[pair.new].Op, segments[pair.new], rightWidth)
+			ref.new = newNum
+			newNum++
+		}
+		rows = append(rows, left+separator+right)
+		lines = append(lines, ref)
+	}
+	return rows, lines
+}
+
+// linePair is a row of the side-by-side view. It holds indices into the
+// fragment's lines, either side is -1 when missing.
+type linePair struct {
+	old int
+	new int
+}
+
+// pairLines lines up each run of deleted lines with the added lines that follow it,
+// so that a modified line shows its old and new versions on the same row.
+func pairLines(lines []gitdiff.Line) []linePair {
+	pairs := make([]linePair, 0, len(lines))
+	for i := 0; i < len(lines); {
+		if lines[i].Op == gitdiff.OpContext {
+			pairs = append(pairs, linePair{old: i, new: i})
+			i++
+			continue
+		}
+
+		deleted := make([]int, 0)
+		for ; i < len(lines) && lines[i].Op == gitdiff.OpDelete; i++ {
+			deleted = append(deleted, i)
+		}
+		added := make([]int, 0)
+		for ; i < len(lines) && lines[i].Op == gitdiff.OpAdd; i++ {
+			added = append(added, i)
+		}
+		for j := 0; j < max(len(deleted), len(added)); j++ {
+			pair := linePair{old: -1, new: -1}
+			if j < len(deleted) {
+				pair.old = deleted[j]
+			}
+			if j < len(added) {
+				pair.new = added[j]
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+func renderCell(gutter string, op gitdiff.LineOp, segments []segment, width int) string {
+	style, emphStyle, sign := contextLineStyle, contextLineStyle, " "
+	switch op {
+	case gitdiff.OpDelete:
+		style, emphStyle, sign = deletedLineStyle, deletedEmphStyle, deletedSignStyle.Inherit(deletedLineStyle).Render("-")
+	case gitdiff.OpAdd:
+		style, emphStyle, sign = addedLineStyle, addedEmphStyle, addedSignStyle.Inherit(addedLineStyle).Render("+")
+	}
+
+	bodyWidth := width - lipgloss.Width(gutter) - 1
+	if bodyWidth <= 0 {
+		return lineNumberStyle.Render(ansi.Truncate(gutter, width, ""))
+	}
+
+	var body strings.Builder
+	for _, seg := range segments {
+		if seg.matched {
+			body.WriteString(searchMatchStyle.Render(seg.text))
+		} else if seg.emphasized {
+			body.WriteString(seg.style.Inherit(emphStyle).Render(seg.text))
+		} else {
+			body.WriteString(seg.style.Inherit(style).Render(seg.text))
+		}
+	}
+	content := ansi.Truncate(body.String(), bodyWidth, "…")
+	padding := style.Render(strings.Repeat(" ", max(0, bodyWidth-ansi.StringWidth(content))))
+	return lineNumberStyle.Render(gutter) + sign + content + padding
+}
+
+// emphasize marks the parts of the segments within the changed byte ranges.
+func emphasize(segments []segment, ranges []worddiff.Range) []segment {
+	return splitRanges(segments, ranges, func(s *segment) { s.emphasized = true })
+}
+
+// markMatches marks the parts of the segments within the search matches.
+func markMatches(segments []segment, ranges []worddiff.Range) []segment {
+	return splitRanges(segments, ranges, func(s *segment) { s.matched = true })
+}
+
+// splitRanges splits the segments at the boundaries of the byte ranges and
+// marks the parts inside them.
+func splitRanges(segments []segment, ranges []worddiff.Range, mark func(*segment)) []segment {
+	if len(ranges) == 0 {
+		return segments
+	}
+	res := make([]segment, 0, len(segments)+2*len(ranges))
+	base, r := 0, 0
+	for _, seg := range segments {
+		end := base + len(seg.text)
+		for start := base; start < end; {
+			for r < len(ranges) && ranges[r].End <= start {
+				r++
+			}
+			cut, inside := end, false
+			if r < len(ranges) && ranges[r].Start <= start {
+				cut, inside = min(end, ranges[r].End), true
+			} else if r < len(ranges) {
+				cut = min(end, ranges[r].Start)
+			}
+			part := seg
+			part.text = seg.text[start-base : cut-base]
+			if inside {
+				mark(&part)
+			}
+			res = append(res, part)
+			start = cut
+		}
+		base = end
+	}
+	return res
+}
+
+func lineNumberWidth(file *gitdiff.File) int {
+	var highest int64 = 0
+	for _, frag := range file.TextFragments {
+		highest = max(highest, frag.OldPosition+frag.OldLines, frag.NewPosition+frag.NewLines)
+	}
+	return len(strconv.FormatInt(highest, 10))
+}
+
+func trimEOL(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			spaces := tabWidth - col%tabWidth
+			b.WriteString(strings.Repeat(" ", spaces))
+			col += spaces
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package diffviewer
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+
+	"github.com/dlvhdr/diffnav/pkg/diffsearch"
+	"github.com/dlvhdr/diffnav/pkg/generated"
+)
+
+const (
+	cacheCapacity = 64
+	// prefetchWorkers bounds how many files are rendered in the background at once.
+	prefetchWorkers = 2
+)
+
+// renderKey identifies a rendered diff, anything that changes the output is part of it.
+type renderKey struct {
+	file       *gitdiff.File
+	width      int
+	sideBySide bool
+	renderer   Renderer
+	search     diffsearch.Query
+	// collapsed is why the diff is replaced by a placeholder, empty when it's shown.
+	collapsed generated.Reason
+	// whitespaceOnly tells that every change of the file was hidden.
+	whitespaceOnly bool
+}
+
+type cacheEntry struct {
+	key      renderKey
+	rendered rendered
+}
+
+// renderCache is an LRU cache of rendered diffs, shared by every copy of the Model.
+type renderCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[renderKey]*list.Element
+	// pending holds the keys being prefetched so they're rendered only once.
+	pending map[renderKey]bool
+}
+
+func newRenderCache(capacity int) *renderCache {
+	return &renderCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[renderKey]*list.Element),
+		pending:  make(map[renderKey]bool),
+	}
+}
+
+func (c *renderCache) get(key renderKey) (rendered, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return rendered{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).rendered, true
+}
+
+func (c *renderCache) put(key renderKey, r rendered) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, key)
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).rendered = r
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, rendered: r})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// reserve marks key as being prefetched, it returns false when it's already
+// cached or in flight.
+func (c *renderCache) reserve(key renderKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok || c.pending[key] {
+		return false
+	}
+	c.pending[key] = true
+	return true
+}
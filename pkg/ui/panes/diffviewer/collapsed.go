@@ -0,0 +1,61 @@
+package diffviewer
+
+import (
+	"fmt"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/generated"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+)
+
+// SetCollapsed sets which files have their diff hidden behind a placeholder
+// until they're expanded, like generated files on GitHub.
+func (m Model) SetCollapsed(collapsed func(*gitdiff.File) generated.Reason) Model {
+	m.collapsed = collapsed
+	return m
+}
+
+// CollapsedReason tells why the diff of file is hidden by default, empty when
+// it isn't.
+func (m Model) CollapsedReason(file *gitdiff.File) generated.Reason {
+	if m.collapsed == nil || file == nil {
+		return ""
+	}
+	return m.collapsed(file)
+}
+
+// IsCollapsed reports whether the diff of file is currently hidden.
+func (m Model) IsCollapsed(file *gitdiff.File) bool {
+	return m.CollapsedReason(file) != "" && !m.expanded[file]
+}
+
+// expand shows the diff of the current file when it's collapsed.
+func (m *Model) expand() tea.Cmd {
+	if !m.IsCollapsed(m.file) {
+		return nil
+	}
+	m.expanded[m.file] = true
+	return m.diff()
+}
+
+// renderCollapsed is the placeholder shown instead of a collapsed diff.
+func renderCollapsed(key renderKey) rendered {
+	lines := []string{key.collapsed.Description()}
+	if k := keys.Get().ExpandDiff.Help().Key; k != "" {
+		lines = append(lines, fmt.Sprintf("Press %s to show the diff.", k))
+	}
+	return renderNotice(lines...)
+}
+
+func (m Model) collapsedBadge() string {
+	reason := m.CollapsedReason(m.file)
+	if reason == "" {
+		return ""
+	}
+	theme := config.Get().Theme
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Muted)).Render("  " + string(reason))
+}
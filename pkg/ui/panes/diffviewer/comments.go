@@ -0,0 +1,328 @@
+package diffviewer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/review"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+)
+
+const (
+	// marginWidth is the column left of the diff that shows the selection and
+	// comment markers.
+	marginWidth  = 1
+	editorHeight = 8
+)
+
+var (
+	selectionMarkerStyle lipgloss.Style
+	commentMarkerStyle   lipgloss.Style
+	matchMarkerStyle     lipgloss.Style
+	editorStyle          lipgloss.Style
+	editorTitleStyle     lipgloss.Style
+)
+
+// CommentsChangedMsg is sent after a comment was added, edited or removed.
+type CommentsChangedMsg struct{}
+
+// lineRef holds the old and new line numbers a row shows, zero when missing.
+type lineRef struct {
+	old int64
+	new int64
+}
+
+func (l lineRef) isZero() bool {
+	return l.old == 0 && l.new == 0
+}
+
+// side picks the side of the row to comment on, the preferred one when the row
+// has both.
+func (l lineRef) side(preferLeft bool) (review.Side, int64) {
+	if l.new == 0 || (preferLeft && l.old != 0) {
+		return review.SideLeft, l.old
+	}
+	return review.SideRight, l.new
+}
+
+// selection is a range of rows being selected to comment on. anchor is where
+// it started and cursor is the row that moves.
+type selection struct {
+	active     bool
+	anchor     int
+	cursor     int
+	preferLeft bool
+}
+
+func (s selection) bounds() (int, int) {
+	return min(s.anchor, s.cursor), max(s.anchor, s.cursor)
+}
+
+// IsSelecting reports whether lines are being selected or a comment is being
+// written, in which case the viewer handles every key.
+func (m Model) IsSelecting() bool {
+	return m.selection.active
+}
+
+func (m Model) selectionUpdate(msg tea.KeyMsg) (Model, tea.Cmd) {
+	km := keys.Get()
+	if m.editing {
+		seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextComment))
+		if !ok {
+			return m, nil
+		}
+		switch {
+		case key.Matches(seq, km.CancelComment):
+			m.closeEditor()
+			return m, nil
+		case key.Matches(seq, km.SaveComment):
+			m.saveComment()
+			m.closeEditor()
+			m.decorate()
+			return m, func() tea.Msg { return CommentsChangedMsg{} }
+		}
+		var cmd tea.Cmd
+		m.editor, cmd = m.editor.Update(msg)
+		return m, cmd
+	}
+
+	seq, ok := m.chord.Feed(msg, km.Bindings(keys.ContextSelection))
+	if !ok {
+		return m, nil
+	}
+	switch {
+	case key.Matches(seq, km.StopSelecting):
+		return m, m.stopSelecting()
+	case key.Matches(seq, km.SelectDown):
+		m.moveSelection(1, false)
+	case key.Matches(seq, km.SelectUp):
+		m.moveSelection(-1, false)
+	case key.Matches(seq, km.ExtendDown):
+		m.moveSelection(1, true)
+	case key.Matches(seq, km.ExtendUp):
+		m.moveSelection(-1, true)
+	case key.Matches(seq, km.SwitchSide):
+		m.selection.preferLeft = !m.selection.preferLeft
+	case key.Matches(seq, km.WriteComment):
+		return m, m.openEditor()
+	}
+	m.decorate()
+	return m, nil
+}
+
+// startSelecting puts a line cursor on the first line shown. Line positions are
+// only known for the builtin renderer, so it's used while selecting.
+func (m *Model) startSelecting() tea.Cmd {
+	m.selection = selection{active: true, anchor: -1, cursor: -1}
+	if m.renderer != RendererBuiltin {
+		return m.diff()
+	}
+	m.placeCursor()
+	m.decorate()
+	return nil
+}
+
+func (m *Model) stopSelecting() tea.Cmd {
+	m.selection = selection{}
+	if m.renderer != RendererBuiltin {
+		return m.diff()
+	}
+	m.decorate()
+	return nil
+}
+
+// placeCursor moves the cursor to the first line in view once the lines are known.
+func (m *Model) placeCursor() {
+	if m.selection.cursor != -1 {
+		return
+	}
+	for i := m.vp.YOffset; i < len(m.lines); i++ {
+		if m.inHunk(i) {
+			m.selection.anchor, m.selection.cursor = i, i
+			return
+		}
+	}
+}
+
+// moveSelection moves the cursor to the next line in the given direction. When
+// extending, the selection stays within a single hunk, as GitHub requires.
+func (m *Model) moveSelection(step int, extend bool) {
+	if m.selection.cursor == -1 {
+		return
+	}
+	i := m.selection.cursor + step
+	for !extend && i >= 0 && i < len(m.lines) && !m.inHunk(i) {
+		i += step
+	}
+	if i < 0 || i >= len(m.lines) || !m.inHunk(i) {
+		return
+	}
+	m.selection.cursor = i
+	if !extend {
+		m.selection.anchor = i
+	}
+
+	if i < m.vp.YOffset {
+		m.vp.SetYOffset(i)
+	} else if i >= m.vp.YOffset+m.vp.Height {
+		m.vp.SetYOffset(i - m.vp.Height + 1)
+	}
+}
+
+// inHunk reports whether row i shows a line of the patch's own hunks. Lines
+// of the context expanded around them can't be commented on, GitHub rejects
+// comments outside of the diff.
+func (m Model) inHunk(i int) bool {
+	ref := m.lines[i]
+	if m.file == nil || ref.isZero() {
+		return false
+	}
+	for _, frag := range m.file.TextFragments {
+		if ref.new != 0 && ref.new >= frag.NewPosition && ref.new < frag.NewPosition+frag.NewLines {
+			return true
+		}
+		if ref.new == 0 && ref.old >= frag.OldPosition && ref.old < frag.OldPosition+frag.OldLines {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the comment the selection would create, without a body.
+func (m Model) target() (review.Comment, bool) {
+	if m.file == nil || m.selection.cursor == -1 {
+		return review.Comment{}, false
+	}
+	first, last := m.selection.bounds()
+	c := review.Comment{Path: filenode.GetFileName(m.file)}
+	c.Side, c.Line = m.lines[last].side(m.selection.preferLeft)
+	if first != last {
+		c.StartSide, c.StartLine = m.lines[first].side(m.selection.preferLeft)
+	}
+	return c, true
+}
+
+func (m *Model) openEditor() tea.Cmd {
+	c, ok := m.target()
+	if !ok {
+		return nil
+	}
+	m.editor = textarea.New()
+	m.editor.ShowLineNumbers = false
+	m.editor.Placeholder = "Leave a comment"
+	m.editor.SetWidth(m.Width - editorStyle.GetHorizontalFrameSize())
+	m.editor.SetHeight(editorHeight - editorStyle.GetVerticalFrameSize() - 1)
+	if existing, ok := m.review.Find(c.Path, c.Side, c.Line); ok {
+		m.editor.SetValue(existing.Body)
+	}
+	m.editing = true
+	m.resizeViewport()
+	m.moveSelection(0, true)
+	return m.editor.Focus()
+}
+
+func (m *Model) closeEditor() {
+	m.editing = false
+	m.editor.Blur()
+	m.resizeViewport()
+}
+
+// saveComment stores the comment being written, an empty comment removes it.
+func (m *Model) saveComment() {
+	c, ok := m.target()
+	if !ok {
+		return
+	}
+	c.Body = strings.TrimSpace(m.editor.Value())
+	if c.Body == "" {
+		m.review.Remove(c.Path, c.Side, c.Line)
+		return
+	}
+	m.review.Set(c)
+}
+
+// decorate draws the margin with the selection, search match and comment
+// markers next to the rendered rows.
+func (m *Model) decorate() {
+	rows := strings.Split(m.text, "\n")
+	commented := m.commentedRows(len(rows))
+	first, last := m.selection.bounds()
+	match := m.matchRow()
+	for i, row := range rows {
+		margin := " "
+		if m.selection.active && m.selection.cursor != -1 && i >= first && i <= last {
+			margin = selectionMarkerStyle.Render("▌")
+		} else if i == match {
+			margin = matchMarkerStyle.Render("▌")
+		} else if commented[i] {
+			margin = commentMarkerStyle.Render("▌")
+		}
+		rows[i] = margin + row
+	}
+	m.vp.SetContent(strings.Join(rows, "\n"))
+}
+
+// commentedRows finds the rows covered by the file's comments.
+func (m Model) commentedRows(count int) []bool {
+	commented := make([]bool, count)
+	if m.file == nil || m.review == nil {
+		return commented
+	}
+	for _, c := range m.review.ForFile(filenode.GetFileName(m.file)) {
+		end := m.rowOf(c.Side, c.Line)
+		start := end
+		if c.StartLine != 0 {
+			start = m.rowOf(c.StartSide, c.StartLine)
+		}
+		if start == -1 || end == -1 {
+			continue
+		}
+		for i := start; i <= end && i < count; i++ {
+			commented[i] = true
+		}
+	}
+	return commented
+}
+
+func (m Model) rowOf(side review.Side, line int64) int {
+	for i, ref := range m.lines {
+		if (side == review.SideLeft && ref.old == line) || (side == review.SideRight && ref.new == line) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m Model) editorView() string {
+	km := keys.Get()
+	c, _ := m.target()
+	save, cancel := km.SaveComment.Help(), km.CancelComment.Help()
+	title := fmt.Sprintf("Comment on line %s · %s %s · %s %s", lineRange(c), save.Key, save.Desc, cancel.Key, cancel.Desc)
+	return editorStyle.Width(m.Width - editorStyle.GetHorizontalFrameSize()).Render(
+		lipgloss.JoinVertical(lipgloss.Left,
+			editorTitleStyle.Render(title),
+			m.editor.View(),
+		),
+	)
+}
+
+func lineRange(c review.Comment) string {
+	line := func(side review.Side, n int64) string {
+		prefix := "R"
+		if side == review.SideLeft {
+			prefix = "L"
+		}
+		return prefix + strconv.FormatInt(n, 10)
+	}
+	if c.StartLine == 0 {
+		return line(c.Side, c.Line)
+	}
+	return line(c.StartSide, c.StartLine) + "-" + line(c.Side, c.Line)
+}
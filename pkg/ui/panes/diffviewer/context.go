@@ -0,0 +1,182 @@
+package diffviewer
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/git"
+	"github.com/dlvhdr/diffnav/pkg/textdiff"
+)
+
+// contextStep is how many lines are shown each time the context of a hunk is
+// expanded, as on GitHub.
+const contextStep = 20
+
+// contextAction is a way of showing more of a file around its hunks.
+type contextAction int
+
+const (
+	contextAbove contextAction = iota
+	contextBelow
+	contextWholeFile
+)
+
+// fileContext is the content of one side of a file, read from the repository
+// to show the lines the patch leaves out.
+type fileContext struct {
+	lines []string
+	old   bool
+	err   error
+	ctx   textdiff.Context
+	whole bool
+	// shown is a copy of the file with the expanded fragments, first maps
+	// them to the fragments of the patch.
+	shown *gitdiff.File
+	first []int
+}
+
+type contextLoadedMsg struct {
+	file    *gitdiff.File
+	context *fileContext
+	action  contextAction
+}
+
+// expandContext shows more lines around the hunk at the top of the viewport,
+// or toggles the whole file. The file's content is read on first use.
+func (m *Model) expandContext(action contextAction) tea.Cmd {
+	file := m.file
+	if file == nil || m.IsCollapsed(file) || len(file.TextFragments) == 0 {
+		return nil
+	}
+	fc, ok := m.contexts[file]
+	if !ok {
+		return func() tea.Msg {
+			return contextLoadedMsg{file: file, context: loadContext(file), action: action}
+		}
+	}
+	if fc.err != nil {
+		return nil
+	}
+	fc.apply(m.baseFile(file), action, m.currentHunk())
+	return m.diff()
+}
+
+func (m *Model) contextLoaded(msg contextLoadedMsg) tea.Cmd {
+	m.contexts[msg.file] = msg.context
+	if msg.context.err != nil {
+		// the header tells why
+		return nil
+	}
+	base := m.baseFile(msg.file)
+	msg.context.reset(base)
+	if msg.file != m.file {
+		return nil
+	}
+	msg.context.apply(base, msg.action, m.currentHunk())
+	return m.diff()
+}
+
+// loadContext reads the new side of the file from its blob, or the old side
+// when the new one isn't in the repository, e.g. for changes that aren't staged.
+func loadContext(file *gitdiff.File) *fileContext {
+	err := errors.New("the patch doesn't tell the file's blobs")
+	for _, side := range []struct {
+		oid string
+		old bool
+	}{{file.NewOIDPrefix, false}, {file.OldOIDPrefix, true}} {
+		if strings.Trim(side.oid, "0") == "" {
+			continue
+		}
+		var content []byte
+		content, err = git.Blob(side.oid)
+		if err != nil {
+			continue
+		}
+		lines := textdiff.SplitLines(string(content))
+		// make sure the blob is the one the patch was made from
+		if err = textdiff.Check(file.TextFragments, lines, side.old); err != nil {
+			continue
+		}
+		return &fileContext{lines: lines, old: side.old}
+	}
+	return &fileContext{err: err}
+}
+
+// reset hides the lines shown around the hunks of base, the file as it's
+// shown before expanding its context. It's called again whenever its
+// fragments change, the whole file stays shown.
+func (fc *fileContext) reset(base *gitdiff.File) {
+	fc.ctx = textdiff.NewContext(len(base.TextFragments))
+	fc.shown = nil
+	_, fc.first = textdiff.Expand(base.TextFragments, fc.lines, fc.old, fc.ctx)
+	if fc.whole {
+		fc.expand(base)
+	}
+}
+
+// apply expands the context around hunk, an index into the shown fragments.
+func (fc *fileContext) apply(base *gitdiff.File, action contextAction, hunk int) {
+	switch action {
+	case contextWholeFile:
+		fc.whole = !fc.whole
+	case contextAbove:
+		if fc.whole || hunk >= len(fc.first) {
+			return
+		}
+		fc.ctx.Up[fc.first[hunk]] += contextStep
+	case contextBelow:
+		if fc.whole || hunk >= len(fc.first) {
+			return
+		}
+		below := len(base.TextFragments)
+		if hunk+1 < len(fc.first) {
+			below = fc.first[hunk+1]
+		}
+		fc.ctx.Down[below] += contextStep
+	}
+	fc.expand(base)
+}
+
+func (fc *fileContext) expand(base *gitdiff.File) {
+	ctx := fc.ctx
+	if fc.whole {
+		ctx = textdiff.NewContext(len(base.TextFragments))
+		for i := range ctx.Down {
+			ctx.Down[i], ctx.Up[i] = len(fc.lines), len(fc.lines)
+		}
+	}
+	shown := *base
+	shown.TextFragments, fc.first = textdiff.Expand(base.TextFragments, fc.lines, fc.old, ctx)
+	fc.shown = &shown
+}
+
+// shownFile returns file the way it's shown, without its whitespace changes
+// when they're hidden and with the lines shown around its hunks.
+func (m Model) shownFile(file *gitdiff.File) *gitdiff.File {
+	if fc, ok := m.contexts[file]; ok && fc.shown != nil {
+		return fc.shown
+	}
+	return m.baseFile(file)
+}
+
+// contextView tells when the whole file is shown, or why its context can't be
+// expanded.
+func (m Model) contextView() string {
+	fc, ok := m.contexts[m.file]
+	if !ok {
+		return ""
+	}
+	style := lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted))
+	switch {
+	case fc.err != nil:
+		return style.Render("  can't expand: " + fc.err.Error())
+	case fc.whole:
+		return style.Render("  whole file")
+	}
+	return ""
+}
@@ -8,25 +8,73 @@ import (
 	"strings"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
 
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/diffsearch"
+	"github.com/dlvhdr/diffnav/pkg/generated"
+	"github.com/dlvhdr/diffnav/pkg/review"
 	"github.com/dlvhdr/diffnav/pkg/ui/common"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
 )
 
 const dirHeaderHeight = 3
 
 type Model struct {
 	common.Common
-	vp     viewport.Model
-	buffer *bytes.Buffer
-	file   *gitdiff.File
+	vp       viewport.Model
+	buffer   *bytes.Buffer
+	file     *gitdiff.File
+	renderer Renderer
+	cache    *renderCache
+	workers  chan struct{}
+	review   *review.Review
+	// text is the rendered diff, before the margin is added.
+	text  string
+	lines []lineRef
+	hunks []int
+	jump  hunkJump
+	// chord collects multi key bindings while lines are selected.
+	chord     keys.Chord
+	selection selection
+	editing   bool
+	editor    textarea.Model
+	// search is highlighted in the diff, match is the line of the current
+	// match and matchPending tells to scroll to it once it's rendered.
+	search       diffsearch.Query
+	match        *diffsearch.Match
+	matchPending bool
+	// collapsed tells why a file's diff is hidden by default, expanded are
+	// the files shown anyway.
+	collapsed func(*gitdiff.File) generated.Reason
+	expanded  map[*gitdiff.File]bool
+	// view lays out every file, it's kept when switching files.
+	view config.ViewMode
+	// contexts hold the content of the files whose context was expanded.
+	contexts map[*gitdiff.File]*fileContext
+	// ignoreWhitespace hides the changes that only touch whitespace,
+	// whitespace caches the files without them.
+	ignoreWhitespace bool
+	whitespace       map[*gitdiff.File]*gitdiff.File
 }
 
-func New() Model {
+func New(renderer Renderer, review *review.Review) Model {
+	applyTheme(config.Get().Theme)
 	return Model{
-		vp: viewport.Model{},
+		vp:         viewport.Model{},
+		renderer:   renderer,
+		cache:      newRenderCache(cacheCapacity),
+		workers:    make(chan struct{}, prefetchWorkers),
+		review:     review,
+		expanded:   make(map[*gitdiff.File]bool),
+		view:       config.Get().View,
+		contexts:   make(map[*gitdiff.File]*fileContext),
+		whitespace: make(map[*gitdiff.File]*gitdiff.File),
 	}
 }
 
@@ -38,19 +86,48 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	cmds := make([]tea.Cmd, 0)
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "down", "j":
-			break
-		case "up", "k":
-			break
-		default:
-			vp, vpCmd := m.vp.Update(msg)
-			cmds = append(cmds, vpCmd)
-			m.vp = vp
+		if m.selection.active {
+			return m.selectionUpdate(msg)
+		}
+
+	case keys.Sequence:
+		km := keys.Get()
+		switch {
+		case key.Matches(msg, km.NextHunk):
+			return m, m.nextHunk()
+		case key.Matches(msg, km.PrevHunk):
+			return m, m.prevHunk()
+		case key.Matches(msg, km.ExpandDiff):
+			return m, m.expand()
+		case key.Matches(msg, km.CycleView):
+			return m, m.cycleView()
+		case key.Matches(msg, km.ExpandAbove):
+			return m, m.expandContext(contextAbove)
+		case key.Matches(msg, km.ExpandBelow):
+			return m, m.expandContext(contextBelow)
+		case key.Matches(msg, km.WholeFile):
+			return m, m.expandContext(contextWholeFile)
+		case key.Matches(msg, km.IgnoreWhitespace):
+			return m, m.toggleWhitespace()
+		case key.Matches(msg, km.Comment):
+			cmds = append(cmds, m.startSelecting())
+		case key.Matches(msg, km.HalfPageDown):
+			m.vp.HalfViewDown()
+		case key.Matches(msg, km.HalfPageUp):
+			m.vp.HalfViewUp()
+		case key.Matches(msg, km.PageDown):
+			m.vp.ViewDown()
+		case key.Matches(msg, km.PageUp):
+			m.vp.ViewUp()
 		}
 
 	case diffContentMsg:
-		m.vp.SetContent(msg.text)
+		if msg.key == m.renderKey(m.file) {
+			m.setContent(msg.rendered)
+		}
+
+	case contextLoadedMsg:
+		cmds = append(cmds, m.contextLoaded(msg))
 	}
 
 	return m, tea.Batch(cmds...)
@@ -60,15 +137,25 @@ func (m Model) View() string {
 	if m.buffer == nil {
 		return "Loading..."
 	}
-	return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.vp.View())
+	if m.editing {
+		return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.pinnedHunkView(), m.vp.View(), m.editorView())
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, m.headerView(), m.pinnedHunkView(), m.vp.View())
 }
 
 func (m *Model) SetSize(width, height int) tea.Cmd {
 	m.Width = width
 	m.Height = height
+	m.resizeViewport()
+	return m.diff()
+}
+
+func (m *Model) resizeViewport() {
 	m.vp.Width = m.Width
-	m.vp.Height = m.Height - dirHeaderHeight
-	return diff(m.file, m.Width)
+	m.vp.Height = m.Height - dirHeaderHeight - pinnedHunkHeight
+	if m.editing {
+		m.vp.Height -= editorHeight
+	}
 }
 
 func (m Model) headerView() string {
@@ -79,21 +166,26 @@ func (m Model) headerView() string {
 	if name == "" {
 		name = m.file.OldName
 	}
+	theme := config.Get().Theme
 	base := lipgloss.NewStyle()
 
 	var added int64 = 0
 	var deleted int64 = 0
-	frags := m.file.TextFragments
+	frags := m.shownFile(m.file).TextFragments
 	for _, frag := range frags {
 		added += frag.LinesAdded
 		deleted += frag.LinesDeleted
 	}
 
-	top := lipgloss.JoinHorizontal(lipgloss.Top, base.Render(""), base.Render(" "), base.Bold(true).Render(name))
+	top := lipgloss.JoinHorizontal(lipgloss.Top, base.Render(""), base.Render(" "), base.Bold(true).Render(name), m.collapsedBadge())
 	bottom := lipgloss.JoinHorizontal(
 		lipgloss.Top,
-		base.Foreground(lipgloss.Color("2")).Render(fmt.Sprintf("  +%d ", added)),
-		base.Foreground(lipgloss.Color("1")).Render(fmt.Sprintf("-%d", deleted)),
+		base.Foreground(lipgloss.Color(theme.Added)).Render(fmt.Sprintf("  +%d ", added)),
+		base.Foreground(lipgloss.Color(theme.Deleted)).Render(fmt.Sprintf("-%d", deleted)),
+		m.renameView(),
+		m.modeView(),
+		m.viewModeView(),
+		m.contextView(),
 	)
 
 	return base.
@@ -102,39 +194,196 @@ func (m Model) headerView() string {
 		Height(dirHeaderHeight - 1).
 		BorderStyle(lipgloss.NormalBorder()).
 		BorderBottom(true).
-		BorderForeground(lipgloss.Color("8")).
+		BorderForeground(lipgloss.Color(theme.Border)).
 		Render(lipgloss.JoinVertical(lipgloss.Left, top, bottom))
 }
 
 func (m Model) SetFilePatch(file *gitdiff.File) (Model, tea.Cmd) {
 	m.buffer = new(bytes.Buffer)
 	m.file = file
-	return m, diff(m.file, m.Width)
+	m.lines = nil
+	m.hunks = nil
+	m.jump = jumpNone
+	m.selection = selection{}
+	m.editing = false
+	m.match = nil
+	m.resizeViewport()
+	m.vp.GotoTop()
+	return m, m.diff()
 }
 
-func diff(file *gitdiff.File, width int) tea.Cmd {
-	if width == 0 || file == nil {
+// Prefetch renders files in the background so that switching to them is instant.
+func (m Model) Prefetch(files ...*gitdiff.File) tea.Cmd {
+	if m.Width == 0 {
 		return nil
 	}
-	return func() tea.Msg {
-		sideBySide := !file.IsNew && !file.IsDelete
-		args := []string{"--paging=never", fmt.Sprintf("-w=%d", width)}
-		if sideBySide {
-			args = append(args, "--side-by-side")
+	cmds := make([]tea.Cmd, 0, len(files))
+	for _, file := range files {
+		if file == nil {
+			continue
 		}
-		deltac := exec.Command("delta", args...)
-		deltac.Env = os.Environ()
-		deltac.Stdin = strings.NewReader(file.String() + "\n")
-		out, err := deltac.Output()
-
-		if err != nil {
-			return common.ErrMsg{Err: err}
+		key := m.renderKey(file)
+		if !m.cache.reserve(key) {
+			continue
 		}
+		cmds = append(cmds, func() tea.Msg {
+			m.workers <- struct{}{}
+			defer func() { <-m.workers }()
+			m.cache.put(key, render(key))
+			return nil
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m Model) renderKey(file *gitdiff.File) renderKey {
+	if file == nil {
+		return renderKey{}
+	}
+	var collapsed generated.Reason
+	if m.IsCollapsed(file) {
+		collapsed = m.CollapsedReason(file)
+	}
+	shown := m.shownFile(file)
+	return renderKey{
+		file:           shown,
+		width:          m.Width - marginWidth,
+		sideBySide:     m.sideBySide(file),
+		renderer:       m.shownRenderer(),
+		search:         m.search,
+		collapsed:      collapsed,
+		whitespaceOnly: m.ignoreWhitespace && len(file.TextFragments) > 0 && len(shown.TextFragments) == 0,
+	}
+}
+
+// diff shows the current file, straight from the cache when it was already rendered.
+func (m *Model) diff() tea.Cmd {
+	if m.file == nil {
+		// every file was filtered out
+		m.setContent(rendered{})
+		return nil
+	}
+	if m.Width == 0 {
+		return nil
+	}
+	key := m.renderKey(m.file)
+	if r, ok := m.cache.get(key); ok {
+		m.setContent(r)
+		return nil
+	}
+	cache := m.cache
+	return func() tea.Msg {
+		r := render(key)
+		cache.put(key, r)
+		return diffContentMsg{key: key, rendered: r}
+	}
+}
+
+func (m *Model) setContent(r rendered) {
+	m.text = r.text
+	m.lines = r.lines
+	m.hunks = r.hunks
+	if m.selection.active {
+		m.placeCursor()
+	}
+	m.decorate()
+	m.applyJump()
+	m.scrollToMatch()
+}
+
+func render(key renderKey) rendered {
+	if key.collapsed != "" {
+		return renderCollapsed(key)
+	}
+	if key.whitespaceOnly {
+		return renderNotice(whitespaceNotice()...)
+	}
+	if notice := fileNotice(key.file); len(notice) > 0 {
+		return renderNotice(notice...)
+	}
+	if key.renderer == RendererBuiltin {
+		return renderBuiltin(key.file, key.width, key.sideBySide, key.search)
+	}
 
-		return diffContentMsg{text: string(out)}
+	args := []string{"--paging=never", fmt.Sprintf("-w=%d", key.width)}
+	if key.sideBySide {
+		args = append(args, "--side-by-side")
 	}
+	deltac := exec.Command("delta", args...)
+	deltac.Env = os.Environ()
+	deltac.Stdin = strings.NewReader(key.file.String() + "\n")
+	out, err := deltac.Output()
+	if err != nil {
+		// delta may be misconfigured or gone since startup, the diff is still
+		// worth showing
+		log.Debug("delta failed, using the builtin renderer", "file", key.file.NewName, "err", err)
+		return renderBuiltin(key.file, key.width, key.sideBySide, key.search)
+	}
+	return rendered{text: string(out), hunks: locateHunks(key.file, string(out))}
 }
 
 type diffContentMsg struct {
-	text string
+	key      renderKey
+	rendered rendered
+}
+
+// SetSearch highlights the matches of q, a zero query clears them.
+func (m Model) SetSearch(q diffsearch.Query) (Model, tea.Cmd) {
+	if q == m.search {
+		return m, nil
+	}
+	m.search = q
+	m.match = nil
+	return m, m.diff()
+}
+
+// Search finds the query in files as they're shown, without the whitespace
+// changes that are hidden and with the context that was expanded, so that
+// every match is on a row of the diff.
+func (m Model) Search(files []*gitdiff.File, q diffsearch.Query) []diffsearch.Match {
+	shown := make([]*gitdiff.File, len(files))
+	original := make(map[*gitdiff.File]*gitdiff.File, len(files))
+	for i, file := range files {
+		shown[i] = m.shownFile(file)
+		original[shown[i]] = file
+	}
+	matches := diffsearch.Search(shown, q)
+	for i := range matches {
+		matches[i].File = original[matches[i].File]
+	}
+	return matches
+}
+
+// ShowMatch marks the match's line and scrolls to it, the match must be in the
+// current file.
+func (m Model) ShowMatch(match diffsearch.Match) Model {
+	m.match = &match
+	m.matchPending = true
+	m.scrollToMatch()
+	m.decorate()
+	return m
+}
+
+// scrollToMatch centers the current match once the diff is rendered.
+func (m *Model) scrollToMatch() {
+	if !m.matchPending || m.match == nil {
+		return
+	}
+	row := m.matchRow()
+	if row == -1 {
+		return
+	}
+	m.vp.SetYOffset(max(0, row-m.vp.Height/2))
+	m.matchPending = false
+}
+
+// matchRow returns the row of the current match, -1 when it's not rendered.
+func (m Model) matchRow() int {
+	if m.match == nil {
+		return -1
+	}
+	if m.match.NewLine != 0 {
+		return m.rowOf(review.SideRight, m.match.NewLine)
+	}
+	return m.rowOf(review.SideLeft, m.match.OldLine)
 }
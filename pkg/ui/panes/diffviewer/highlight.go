@@ -0,0 +1,133 @@
+package diffviewer
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+)
+
+// segment is a run of text within a line that shares a single style.
+type segment struct {
+	text  string
+	style lipgloss.Style
+	// emphasized marks text that changed within a modified line.
+	emphasized bool
+	// matched marks text that matches the search.
+	matched bool
+}
+
+// highlighter colors fragment lines with a chroma lexer picked for the file.
+type highlighter struct {
+	lexer chroma.Lexer
+	style *chroma.Style
+}
+
+// newHighlighter picks a lexer from the file's extension, falling back to
+// sniffing a shebang. It returns nil when the language can't be detected.
+func newHighlighter(file *gitdiff.File) *highlighter {
+	lexer := lexers.Match(filenode.GetFileName(file))
+	if lexer == nil {
+		if shebang := firstLine(file); strings.HasPrefix(shebang, "#!") {
+			lexer = lexers.Analyse(shebang)
+		}
+	}
+	if lexer == nil {
+		return nil
+	}
+	return &highlighter{lexer: chroma.Coalesce(lexer), style: styles.Get(config.Get().Theme.Syntax)}
+}
+
+// highlightFragment returns the styled segments of every line in the fragment,
+// indexed like frag.Lines. The old and new sides are each tokenized as a whole so
+// constructs that span lines, like block comments and strings, are colored right.
+func (h *highlighter) highlightFragment(frag *gitdiff.TextFragment) [][]segment {
+	segments := plainSegments(frag)
+	if h == nil {
+		return segments
+	}
+
+	oldIdx, newIdx := make([]int, 0), make([]int, 0)
+	var oldSrc, newSrc strings.Builder
+	for i, line := range frag.Lines {
+		text := lineText(line) + "\n"
+		if line.Old() {
+			oldIdx = append(oldIdx, i)
+			oldSrc.WriteString(text)
+		}
+		if line.New() {
+			newIdx = append(newIdx, i)
+			newSrc.WriteString(text)
+		}
+	}
+
+	h.apply(segments, oldIdx, oldSrc.String())
+	h.apply(segments, newIdx, newSrc.String())
+	return segments
+}
+
+func (h *highlighter) apply(segments [][]segment, indices []int, src string) {
+	it, err := h.lexer.Tokenise(nil, src)
+	if err != nil {
+		return
+	}
+	for i, tokens := range chroma.SplitTokensIntoLines(it.Tokens()) {
+		if i >= len(indices) {
+			break
+		}
+		line := make([]segment, 0, len(tokens))
+		for _, token := range tokens {
+			text := strings.TrimSuffix(token.Value, "\n")
+			if text == "" {
+				continue
+			}
+			line = append(line, segment{text: text, style: h.tokenStyle(token.Type)})
+		}
+		segments[indices[i]] = line
+	}
+}
+
+func (h *highlighter) tokenStyle(t chroma.TokenType) lipgloss.Style {
+	entry := h.style.Get(t)
+	st := lipgloss.NewStyle()
+	if entry.Colour.IsSet() {
+		st = st.Foreground(lipgloss.Color(entry.Colour.String()))
+	}
+	if entry.Bold == chroma.Yes {
+		st = st.Bold(true)
+	}
+	if entry.Italic == chroma.Yes {
+		st = st.Italic(true)
+	}
+	return st
+}
+
+func plainSegments(frag *gitdiff.TextFragment) [][]segment {
+	segments := make([][]segment, len(frag.Lines))
+	for i, line := range frag.Lines {
+		segments[i] = []segment{{text: lineText(line), style: lipgloss.NewStyle()}}
+	}
+	return segments
+}
+
+// lineText is the line's content as it should be displayed.
+func lineText(line gitdiff.Line) string {
+	return expandTabs(trimEOL(line.Line))
+}
+
+func firstLine(file *gitdiff.File) string {
+	if len(file.TextFragments) == 0 {
+		return ""
+	}
+	frag := file.TextFragments[0]
+	if len(frag.Lines) == 0 || (frag.OldPosition > 1 && frag.NewPosition > 1) {
+		return ""
+	}
+	return trimEOL(frag.Lines[0].Line)
+}
@@ -0,0 +1,204 @@
+package diffviewer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	pinnedHunkHeight = 1
+	// matchPrefixLen is how much of a line is used to find it in delta's output,
+	// long lines may be truncated or wrapped there.
+	matchPrefixLen = 20
+)
+
+// NextFileMsg asks to move on to the next file after the last hunk.
+type NextFileMsg struct{}
+
+// PrevFileMsg asks to move back to the previous file before the first hunk.
+type PrevFileMsg struct{}
+
+// hunkJump is a hunk to scroll to once the file's diff is rendered.
+type hunkJump int
+
+const (
+	jumpNone hunkJump = iota
+	jumpFirst
+	jumpLast
+)
+
+// rendered is a rendered diff along with the line each fragment's content
+// starts on. lines tells which lines of the file each row shows, it's only known
+// for the builtin renderer.
+type rendered struct {
+	text  string
+	hunks []int
+	lines []lineRef
+}
+
+func (m *Model) nextHunk() tea.Cmd {
+	if !m.vp.AtBottom() {
+		for _, offset := range m.hunks {
+			if offset > m.vp.YOffset {
+				m.vp.SetYOffset(offset)
+				return nil
+			}
+		}
+	}
+	return func() tea.Msg { return NextFileMsg{} }
+}
+
+func (m *Model) prevHunk() tea.Cmd {
+	for i := len(m.hunks) - 1; i >= 0; i-- {
+		if m.hunks[i] < m.vp.YOffset {
+			m.vp.SetYOffset(m.hunks[i])
+			return nil
+		}
+	}
+	return func() tea.Msg { return PrevFileMsg{} }
+}
+
+// JumpToFirstHunk scrolls to the first hunk once the current file is rendered.
+func (m Model) JumpToFirstHunk() Model {
+	m.jump = jumpFirst
+	m.applyJump()
+	return m
+}
+
+// JumpToLastHunk scrolls to the last hunk once the current file is rendered.
+func (m Model) JumpToLastHunk() Model {
+	m.jump = jumpLast
+	m.applyJump()
+	return m
+}
+
+func (m *Model) applyJump() {
+	if m.jump == jumpNone || len(m.hunks) == 0 {
+		return
+	}
+	if m.jump == jumpFirst {
+		m.vp.SetYOffset(m.hunks[0])
+	} else {
+		m.vp.SetYOffset(m.hunks[len(m.hunks)-1])
+	}
+	m.jump = jumpNone
+}
+
+// currentHunk is the index of the hunk shown at the top of the viewport.
+func (m Model) currentHunk() int {
+	current := 0
+	for i, offset := range m.hunks {
+		if offset <= m.vp.YOffset {
+			current = i
+		}
+	}
+	return current
+}
+
+// currentFragment is the fragment shown at the top of the viewport.
+func (m Model) currentFragment() *gitdiff.TextFragment {
+	if m.file == nil {
+		return nil
+	}
+	frags := m.shownFile(m.file).TextFragments
+	if len(m.hunks) != len(frags) || len(m.hunks) == 0 {
+		return nil
+	}
+	return frags[m.currentHunk()]
+}
+
+func (m Model) pinnedHunkView() string {
+	frag := m.currentFragment()
+	if frag == nil {
+		return ""
+	}
+	return renderHunkHeader(frag, m.Width)
+}
+
+// locateHunks finds where each fragment starts in delta's output. The layout
+// of hunk headers depends on the user's delta config, so a header is found by
+// its raw "@@" line or by the line number delta writes instead, after the file
+// name or not. When delta leaves headers out, the first non blank line of the
+// fragment is looked up instead.
+func locateHunks(file *gitdiff.File, out string) []int {
+	rows := strings.Split(out, "\n")
+	for i, row := range rows {
+		rows[i] = normalize(ansi.Strip(row))
+	}
+
+	hunks := make([]int, 0, len(file.TextFragments))
+	row := 0
+	for _, frag := range file.TextFragments {
+		found := findRow(rows, row, func(r string) bool { return isHunkHeader(r, file, frag) })
+		if found == -1 {
+			target := ""
+			for _, line := range frag.Lines {
+				if target = normalize(line.Line); target != "" {
+					break
+				}
+			}
+			if runes := []rune(target); len(runes) > matchPrefixLen {
+				target = string(runes[:matchPrefixLen])
+			}
+			found = findRow(rows, row, func(r string) bool { return target != "" && strings.Contains(r, target) })
+		}
+		if found == -1 {
+			hunks = append(hunks, row)
+			continue
+		}
+		hunks = append(hunks, found)
+		row = found + 1
+	}
+	return hunks
+}
+
+// findRow returns the first row from start that matches, or -1.
+func findRow(rows []string, start int, match func(string) bool) int {
+	for i := start; i < len(rows); i++ {
+		if match(rows[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// isHunkHeader reports whether a normalized row of delta's output is the
+// header of frag, either raw or as "line:" or "file:line:" in a box.
+func isHunkHeader(row string, file *gitdiff.File, frag *gitdiff.TextFragment) bool {
+	row = strings.TrimLeftFunc(row, isBoxDrawing)
+	raw := fmt.Sprintf("@@-%d,%d+%d,%d@@", frag.OldPosition, frag.OldLines, frag.NewPosition, frag.NewLines)
+	if strings.HasPrefix(row, raw) {
+		return true
+	}
+	for _, line := range []int64{frag.NewPosition, frag.OldPosition} {
+		number := fmt.Sprintf("%d:", line)
+		if strings.HasPrefix(row, number) {
+			return true
+		}
+		for _, name := range []string{file.NewName, file.OldName} {
+			if name != "" && strings.HasPrefix(row, normalize(name)+":"+number) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBoxDrawing(r rune) bool {
+	return r >= '\u2500' && r <= '\u257f'
+}
+
+// normalize drops whitespace, which delta may expand or wrap differently.
+func normalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
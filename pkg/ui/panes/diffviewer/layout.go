@@ -0,0 +1,68 @@
+package diffviewer
+
+import (
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+)
+
+// ViewChangedMsg is sent after the view mode was changed from the UI, to
+// remember it for the next runs.
+type ViewChangedMsg struct {
+	View config.ViewMode
+}
+
+// SetView changes how the diffs are laid out.
+func (m Model) SetView(view config.ViewMode) (Model, tea.Cmd) {
+	if view == m.view {
+		return m, nil
+	}
+	m.view = view
+	return m, m.diff()
+}
+
+// cycleView switches to the next view mode.
+func (m *Model) cycleView() tea.Cmd {
+	view := m.view.Next()
+	var cmd tea.Cmd
+	*m, cmd = m.SetView(view)
+	return tea.Batch(cmd, func() tea.Msg { return ViewChangedMsg{View: view} })
+}
+
+// sideBySide tells whether file is shown side by side. The auto view only does
+// when the diff is wide enough, and when both sides of the file have lines.
+func (m Model) sideBySide(file *gitdiff.File) bool {
+	switch m.view {
+	case config.ViewUnified:
+		return false
+	case config.ViewSideBySide:
+		return true
+	default:
+		return m.Width >= config.Get().SideBySideWidth && !file.IsNew && !file.IsDelete
+	}
+}
+
+// viewModeView tells how the diff is laid out, and why when it's automatic.
+func (m Model) viewModeView() string {
+	if m.IsCollapsed(m.file) || len(fileNotice(m.file)) > 0 {
+		return ""
+	}
+	text := "  unified"
+	if m.sideBySide(m.file) {
+		text = "  side-by-side"
+	}
+	if m.view == config.ViewAuto {
+		text += " (auto)"
+	}
+	if m.shownRenderer() != m.renderer {
+		text += " · builtin while "
+		if m.selection.active {
+			text += "commenting"
+		} else {
+			text += "searching"
+		}
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted)).Render(text)
+}
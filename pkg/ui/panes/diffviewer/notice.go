@@ -0,0 +1,199 @@
+package diffviewer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+)
+
+// fileNotice describes the changes of file that aren't lines of text: binary
+// content, submodule commits, mode changes and renames without changes. It's
+// empty when the diff has lines to show.
+func fileNotice(file *gitdiff.File) []string {
+	switch {
+	case filenode.IsSubmodule(file):
+		return submoduleNotice(file)
+	case file.IsBinary:
+		return binaryNotice(file)
+	case len(file.TextFragments) > 0:
+		return nil
+	}
+
+	lines := make([]string, 0, 2)
+	switch {
+	case file.IsRename:
+		lines = append(lines, "File renamed without changes.")
+	case file.IsCopy:
+		lines = append(lines, "File copied without changes.")
+	case file.IsNew:
+		lines = append(lines, "Empty file added.")
+	case file.IsDelete:
+		lines = append(lines, "Empty file deleted.")
+	}
+	if filenode.ModeChanged(file) {
+		lines = append(lines, fmt.Sprintf("File mode changed from %o to %o.", file.OldMode, file.NewMode))
+	}
+	return lines
+}
+
+func binaryNotice(file *gitdiff.File) []string {
+	oldSize, newSize, ok := binarySizes(file)
+	switch {
+	case !ok:
+		return []string{"Binary file changed.", "Its size is unknown, the patch doesn't include binary content (see git diff --binary)."}
+	case file.IsNew:
+		return []string{"Binary file added, " + formatSize(newSize) + "."}
+	case file.IsDelete:
+		return []string{"Binary file deleted, it was " + formatSize(oldSize) + "."}
+	}
+	delta := formatSize(abs(newSize - oldSize))
+	if newSize >= oldSize {
+		delta = "+" + delta
+	} else {
+		delta = "-" + delta
+	}
+	return []string{"Binary file changed.", fmt.Sprintf("%s → %s (%s)", formatSize(oldSize), formatSize(newSize), delta)}
+}
+
+// binarySizes returns the size of the old and new content of a binary file,
+// ok is false when the patch doesn't include them.
+func binarySizes(file *gitdiff.File) (oldSize, newSize int64, ok bool) {
+	oldSize, newSize, ok = fragmentSizes(file.BinaryFragment)
+	if !ok {
+		return 0, 0, false
+	}
+	if oldSize == -1 {
+		// a literal fragment only has the new content, the old one is in the
+		// reverse fragment
+		_, oldSize, ok = fragmentSizes(file.ReverseBinaryFragment)
+		if file.IsNew {
+			oldSize, ok = 0, true
+		}
+	}
+	return oldSize, newSize, ok
+}
+
+// fragmentSizes returns the size of the content a binary fragment applies to
+// and of the content it produces. The former is -1 for literal fragments,
+// which don't depend on it.
+func fragmentSizes(frag *gitdiff.BinaryFragment) (int64, int64, bool) {
+	if frag == nil {
+		return 0, 0, false
+	}
+	if frag.Method == gitdiff.BinaryPatchLiteral {
+		return -1, frag.Size, true
+	}
+	// a delta starts with the sizes of its source and target as varints
+	src, n := binary.Uvarint(frag.Data)
+	if n <= 0 {
+		return 0, 0, false
+	}
+	dst, m := binary.Uvarint(frag.Data[n:])
+	if m <= 0 {
+		return 0, 0, false
+	}
+	return int64(src), int64(dst), true
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, suffix := float64(size)/unit, "KiB"
+	for _, next := range []string{"MiB", "GiB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, next
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func submoduleNotice(file *gitdiff.File) []string {
+	oldCommit, newCommit := submoduleCommits(file)
+	switch {
+	case file.IsNew:
+		return []string{"Submodule added at " + shortCommit(newCommit) + "."}
+	case file.IsDelete:
+		return []string{"Submodule removed, it was at " + shortCommit(oldCommit) + "."}
+	}
+	return []string{"Submodule updated.", shortCommit(oldCommit) + " → " + shortCommit(newCommit)}
+}
+
+// submoduleCommits finds the commits a submodule pointed to before and after
+// the change, in the "Subproject commit" lines of its diff.
+func submoduleCommits(file *gitdiff.File) (string, string) {
+	oldCommit, newCommit := file.OldOIDPrefix, file.NewOIDPrefix
+	for _, frag := range file.TextFragments {
+		for _, line := range frag.Lines {
+			commit, ok := strings.CutPrefix(strings.TrimSpace(line.Line), "Subproject commit ")
+			if !ok {
+				continue
+			}
+			switch line.Op {
+			case gitdiff.OpDelete:
+				oldCommit = commit
+			case gitdiff.OpAdd:
+				newCommit = commit
+			}
+		}
+	}
+	return oldCommit, newCommit
+}
+
+// shortCommit abbreviates a commit hash, keeping a suffix like "-dirty".
+func shortCommit(commit string) string {
+	hash, suffix, _ := strings.Cut(commit, "-")
+	if len(hash) > 7 {
+		hash = hash[:7]
+	}
+	if suffix != "" {
+		return hash + "-" + suffix
+	}
+	return hash
+}
+
+// renderNotice is shown instead of a diff that has nothing to show, or that
+// is collapsed.
+func renderNotice(lines ...string) rendered {
+	return rendered{text: placeholderStyle.Render("\n  " + strings.Join(lines, "\n  "))}
+}
+
+// renameView tells which file a renamed or copied file comes from, and how
+// similar they are.
+func (m Model) renameView() string {
+	if !m.file.IsRename && !m.file.IsCopy {
+		return ""
+	}
+	text := "  renamed from " + m.file.OldName
+	if m.file.IsCopy {
+		text = "  copied from " + m.file.OldName
+	}
+	if m.file.Score > 0 {
+		text += fmt.Sprintf(" · %d%% similar", m.file.Score)
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted)).Render(text)
+}
+
+// modeView tells how the mode of the file changed.
+func (m Model) modeView() string {
+	if !filenode.ModeChanged(m.file) || filenode.IsSubmodule(m.file) {
+		return ""
+	}
+	text := fmt.Sprintf("  mode %o → %o", m.file.OldMode, m.file.NewMode)
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Muted)).Render(text)
+}
@@ -0,0 +1,53 @@
+package diffviewer
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/charmbracelet/log"
+)
+
+// Renderer is the backend used to turn a file patch into the text shown in the viewer.
+type Renderer string
+
+const (
+	RendererDelta   Renderer = "delta"
+	RendererBuiltin Renderer = "builtin"
+)
+
+// ParseRenderer validates a renderer name, falling back to DefaultRenderer when
+// it's empty. Delta falls back to the builtin renderer when it isn't installed,
+// so that a config shared between machines works on all of them.
+func ParseRenderer(name string) (Renderer, error) {
+	switch Renderer(name) {
+	case "":
+		return DefaultRenderer(), nil
+	case RendererDelta:
+		if _, err := exec.LookPath("delta"); err != nil {
+			log.Debug("delta isn't installed, using the builtin renderer", "err", err)
+			return RendererBuiltin, nil
+		}
+		return RendererDelta, nil
+	case RendererBuiltin:
+		return Renderer(name), nil
+	}
+	return "", fmt.Errorf("unknown renderer %q, expected %q or %q", name, RendererBuiltin, RendererDelta)
+}
+
+// DefaultRenderer prefers delta when it's installed and the builtin renderer otherwise.
+func DefaultRenderer() Renderer {
+	if _, err := exec.LookPath("delta"); err != nil {
+		return RendererBuiltin
+	}
+	return RendererDelta
+}
+
+// shownRenderer is the renderer the diff is drawn with. Line positions and
+// match highlights are only known for the builtin renderer, so it replaces
+// delta while selecting lines or searching.
+func (m Model) shownRenderer() Renderer {
+	if m.selection.active || !m.search.IsZero() {
+		return RendererBuiltin
+	}
+	return m.renderer
+}
@@ -0,0 +1,70 @@
+package diffviewer
+
+import (
+	"fmt"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/textdiff"
+	"github.com/dlvhdr/diffnav/pkg/ui/keys"
+)
+
+// WhitespaceChangedMsg is sent after whitespace changes were hidden or shown
+// again, which changes the files that have something to show.
+type WhitespaceChangedMsg struct{}
+
+// IgnoresWhitespace reports whether changes that only touch whitespace are
+// hidden.
+func (m Model) IgnoresWhitespace() bool {
+	return m.ignoreWhitespace
+}
+
+// WhitespaceOnly reports whether file has nothing left to show once its
+// whitespace changes are hidden.
+func (m Model) WhitespaceOnly(file *gitdiff.File) bool {
+	if len(file.TextFragments) == 0 || file.IsNew || file.IsDelete || file.IsRename || file.IsCopy || filenode.ModeChanged(file) {
+		return false
+	}
+	return len(m.withoutWhitespace(file).TextFragments) == 0
+}
+
+// toggleWhitespace hides or shows the changes that only touch whitespace.
+func (m *Model) toggleWhitespace() tea.Cmd {
+	m.ignoreWhitespace = !m.ignoreWhitespace
+	// the hunks the context was expanded around changed
+	for file, fc := range m.contexts {
+		if fc.err == nil {
+			fc.reset(m.baseFile(file))
+		}
+	}
+	return tea.Batch(m.diff(), func() tea.Msg { return WhitespaceChangedMsg{} })
+}
+
+// baseFile returns file without its whitespace changes when they're hidden.
+func (m Model) baseFile(file *gitdiff.File) *gitdiff.File {
+	if !m.ignoreWhitespace {
+		return file
+	}
+	return m.withoutWhitespace(file)
+}
+
+func (m Model) withoutWhitespace(file *gitdiff.File) *gitdiff.File {
+	if f, ok := m.whitespace[file]; ok {
+		return f
+	}
+	f := *file
+	f.TextFragments = textdiff.IgnoreWhitespace(file.TextFragments)
+	m.whitespace[file] = &f
+	return &f
+}
+
+// whitespaceNotice is shown instead of a diff that only changes whitespace.
+func whitespaceNotice() []string {
+	lines := []string{"Only whitespace changed."}
+	if k := keys.Get().IgnoreWhitespace.Help().Key; k != "" {
+		lines = append(lines, fmt.Sprintf("Press %s to show whitespace changes.", k))
+	}
+	return lines
+}
@@ -1,8 +1,7 @@
 package filetree
 
 import (
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/bluekeyes/go-gitdiff/gitdiff"
@@ -11,82 +10,277 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/tree"
 
-	"github.com/dlvhdr/diffnav/pkg/constants"
+	"github.com/dlvhdr/diffnav/pkg/config"
+	"github.com/dlvhdr/diffnav/pkg/dirnode"
 	"github.com/dlvhdr/diffnav/pkg/filenode"
-	"github.com/dlvhdr/diffnav/pkg/utils"
 )
 
 type Model struct {
-	files        []*gitdiff.File
-	tree         *tree.Tree
-	vp           viewport.Model
-	selectedFile *string
+	files []*gitdiff.File
+	// nodes are the top level directories and files.
+	nodes []tree.Node
+	// rows are the visible nodes in display order, the cursor indexes them.
+	rows      []tree.Node
+	cursor    int
+	collapsed map[string]bool
+	isViewed  func(*gitdiff.File) bool
+	comments  func(*gitdiff.File) int
+	generated func(*gitdiff.File) bool
+	// whitespaceOnly marks the files that only changed whitespace.
+	whitespaceOnly func(*gitdiff.File) bool
+	vp             viewport.Model
+}
+
+func New() Model {
+	return Model{
+		files:     []*gitdiff.File{},
+		collapsed: make(map[string]bool),
+		vp:        viewport.Model{},
+	}
+}
+
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	m.vp, _ = m.vp.Update(msg)
+	return m, nil
+}
+
+func (m Model) View() string {
+	return m.vp.View()
+}
+
+// SetSize implements the Component interface.
+func (m *Model) SetSize(width, height int) tea.Cmd {
+	m.vp.Width = width
+	m.vp.Height = height
+	return nil
 }
 
 func (m Model) SetFiles(files []*gitdiff.File) Model {
+	var selected tree.Node
+	if m.cursor < len(m.rows) {
+		selected = m.rows[m.cursor]
+	}
+
 	m.files = files
-	t := buildFullFileTree(files)
-	collapsed := collapseTree(t)
-	m.tree, _ = truncateTree(collapsed, 0, 0, 0)
-	m.vp.SetContent(m.printWithoutRoot())
-	return m
+	m.nodes = buildTree(files, m.collapsed)
+	annotate(m.nodes, m.isViewed, m.comments, m.generated, m.whitespaceOnly)
+	m.rows = flatten(m.nodes, false)
+	m.cursor = m.indexOf(selected)
+	if m.cursor == -1 {
+		m.cursor = max(0, slices.IndexFunc(m.rows, func(node tree.Node) bool {
+			_, ok := node.(filenode.FileNode)
+			return ok
+		}))
+	}
+	return m.refresh()
 }
 
-func (m Model) SetCursor(cursor int) Model {
-	if len(m.files) == 0 {
+// SelectedFile returns the file under the cursor, or nil when it's on a directory.
+func (m Model) SelectedFile() *gitdiff.File {
+	if m.cursor >= len(m.rows) {
+		return nil
+	}
+	if node, ok := m.rows[m.cursor].(filenode.FileNode); ok {
+		return node.File
+	}
+	return nil
+}
+
+// SelectedFiles returns the file under the cursor, or every file of the
+// directory under it.
+func (m Model) SelectedFiles() []*gitdiff.File {
+	if dir, ok := m.selectedDir(); ok {
+		return dir.Files()
+	}
+	if file := m.SelectedFile(); file != nil {
+		return []*gitdiff.File{file}
+	}
+	return nil
+}
+
+// SetViewed updates the viewed marks of the files.
+func (m Model) SetViewed(isViewed func(*gitdiff.File) bool) Model {
+	m.isViewed = isViewed
+	annotate(m.nodes, m.isViewed, m.comments, m.generated, m.whitespaceOnly)
+	m.rows = flatten(m.nodes, false)
+	return m.refresh()
+}
+
+// SetComments updates the comment count of the files.
+func (m Model) SetComments(comments func(*gitdiff.File) int) Model {
+	m.comments = comments
+	annotate(m.nodes, m.isViewed, m.comments, m.generated, m.whitespaceOnly)
+	m.rows = flatten(m.nodes, false)
+	return m.refresh()
+}
+
+// SetGenerated updates the generated marks of the files.
+func (m Model) SetGenerated(generated func(*gitdiff.File) bool) Model {
+	m.generated = generated
+	annotate(m.nodes, m.isViewed, m.comments, m.generated, m.whitespaceOnly)
+	m.rows = flatten(m.nodes, false)
+	return m.refresh()
+}
+
+// SetWhitespaceOnly updates the marks of the files that only changed
+// whitespace.
+func (m Model) SetWhitespaceOnly(whitespaceOnly func(*gitdiff.File) bool) Model {
+	m.whitespaceOnly = whitespaceOnly
+	annotate(m.nodes, m.isViewed, m.comments, m.generated, m.whitespaceOnly)
+	m.rows = flatten(m.nodes, false)
+	return m.refresh()
+}
+
+// SelectFile moves the cursor to the file, expanding its parent directories.
+func (m Model) SelectFile(file *gitdiff.File) Model {
+	for _, dir := range ancestors(m.nodes, file) {
+		dir.Collapsed = false
+		delete(m.collapsed, dir.Path)
+	}
+	m.rows = flatten(m.nodes, false)
+	m.cursor = max(0, m.indexOf(filenode.FileNode{File: file}))
+	return m.refresh()
+}
+
+// CursorUp moves to the previous row, passing over the files skip returns true
+// for. skip may be nil.
+func (m Model) CursorUp(skip func(*gitdiff.File) bool) Model {
+	return m.moveCursor(-1, skip)
+}
+
+// CursorDown moves to the next row, passing over the files skip returns true
+// for. skip may be nil.
+func (m Model) CursorDown(skip func(*gitdiff.File) bool) Model {
+	return m.moveCursor(1, skip)
+}
+
+func (m Model) moveCursor(step int, skip func(*gitdiff.File) bool) Model {
+	for i := m.cursor + step; i >= 0 && i < len(m.rows); i += step {
+		if node, ok := m.rows[i].(filenode.FileNode); ok && skip != nil && skip(node.File) {
+			continue
+		}
+		m.cursor = i
+		break
+	}
+	return m.refresh()
+}
+
+// Toggle collapses or expands the directory under the cursor.
+func (m Model) Toggle() Model {
+	dir, ok := m.selectedDir()
+	if !ok {
 		return m
 	}
-	name := filenode.GetFileName(m.files[cursor])
-	m.selectedFile = &name
-	applyStyles(m.tree, m.selectedFile)
-	m.scrollSelectedFileIntoView(m.tree)
-	m.vp.SetContent(m.printWithoutRoot())
+	return m.setCollapsed(dir, !dir.Collapsed)
+}
+
+// Collapse folds the directory under the cursor. On a file or an already
+// collapsed directory it moves the cursor to the parent directory instead.
+func (m Model) Collapse() Model {
+	if dir, ok := m.selectedDir(); ok && !dir.Collapsed {
+		return m.setCollapsed(dir, true)
+	}
+	if m.cursor >= len(m.rows) {
+		return m
+	}
+	if parent := m.parentOf(m.rows[m.cursor]); parent != nil {
+		m.cursor = m.indexOf(parent)
+	}
+	return m.refresh()
+}
+
+// Expand unfolds the directory under the cursor.
+func (m Model) Expand() Model {
+	if dir, ok := m.selectedDir(); ok && dir.Collapsed {
+		return m.setCollapsed(dir, false)
+	}
 	return m
 }
 
-const contextLines = 15
+// OrderedFiles returns the files in the order they appear in the tree,
+// including those within collapsed directories.
+func (m Model) OrderedFiles() []*gitdiff.File {
+	files := make([]*gitdiff.File, 0, len(m.files))
+	for _, node := range flatten(m.nodes, true) {
+		if node, ok := node.(filenode.FileNode); ok {
+			files = append(files, node.File)
+		}
+	}
+	return files
+}
 
-func (m *Model) scrollSelectedFileIntoView(t *tree.Tree) {
-	children := t.Children()
-	found := false
-	for i := 0; i < children.Length(); i++ {
-		child := children.At(i)
-		switch child := child.(type) {
-		case *tree.Tree:
-			m.scrollSelectedFileIntoView(child)
-		case filenode.FileNode:
-			if child.Path() == *m.selectedFile {
-				// offset is 1-based, so we need to subtract 1
-				offset := child.YOffset - 1 - contextLines
-				// we also need to subtract 1 if the root is not shown
-				if m.tree.Value() == "." {
-					offset = offset - 1
-				}
-				m.vp.SetYOffset(offset)
-				found = true
-				break
+// FileAfter returns the file offset positions away from file in display order,
+// including files inside collapsed directories, or nil when there's none.
+func (m Model) FileAfter(file *gitdiff.File, offset int) *gitdiff.File {
+	files := m.OrderedFiles()
+	for i, f := range files {
+		if f == file {
+			if i+offset >= 0 && i+offset < len(files) {
+				return files[i+offset]
 			}
-		}
-		if found {
-			break
+			return nil
 		}
 	}
+	return nil
 }
 
-func New() Model {
-	return Model{
-		files: []*gitdiff.File{},
-		vp:    viewport.Model{},
+func (m Model) setCollapsed(dir *dirnode.DirNode, collapsed bool) Model {
+	dir.Collapsed = collapsed
+	if collapsed {
+		m.collapsed[dir.Path] = true
+	} else {
+		delete(m.collapsed, dir.Path)
 	}
+	m.rows = flatten(m.nodes, false)
+	m.cursor = max(0, m.indexOf(dir))
+	return m.refresh()
 }
 
-func (m Model) Init() tea.Cmd {
-	return nil
+func (m Model) selectedDir() (*dirnode.DirNode, bool) {
+	if m.cursor >= len(m.rows) {
+		return nil, false
+	}
+	dir, ok := m.rows[m.cursor].(*dirnode.DirNode)
+	return dir, ok
 }
 
-func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
-	m.vp, _ = m.vp.Update(msg)
-	return m, nil
+func (m Model) indexOf(node tree.Node) int {
+	for i, row := range m.rows {
+		if sameNode(row, node) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m Model) parentOf(node tree.Node) *dirnode.DirNode {
+	var find func(nodes []tree.Node, parent *dirnode.DirNode) *dirnode.DirNode
+	find = func(nodes []tree.Node, parent *dirnode.DirNode) *dirnode.DirNode {
+		for _, n := range nodes {
+			if sameNode(n, node) {
+				return parent
+			}
+			if dir, ok := n.(*dirnode.DirNode); ok {
+				if found := find(dir.Items, dir); found != nil {
+					return found
+				}
+			}
+		}
+		return nil
+	}
+	return find(m.nodes, nil)
+}
+
+const contextLines = 15
+
+func (m Model) refresh() Model {
+	m.vp.SetContent(m.render())
+	m.vp.SetYOffset(m.cursor - contextLines)
+	return m
 }
 
 var indenter = func(children tree.Children, index int) string {
@@ -103,203 +297,191 @@ var enumerator = func(children tree.Children, index int) string {
 	return "├"
 }
 
-func (m Model) View() string {
-	return m.vp.View()
-}
+// render prints the top level nodes without a common root.
+func (m Model) render() string {
+	selected := tree.Node(nil)
+	if m.cursor < len(m.rows) {
+		selected = m.rows[m.cursor]
+	}
 
-// SetSize implements the Component interface.
-func (m *Model) SetSize(width, height int) tea.Cmd {
-	m.vp.Width = width
-	m.vp.Height = height
-	return nil
+	s := make([]string, 0, len(m.nodes))
+	for _, node := range m.nodes {
+		switch node := node.(type) {
+		case *dirnode.DirNode:
+			t := tree.Root(node.Value()).Child(node.Children())
+			applyStyles(t, selected)
+			t.RootStyle(applyStyleToNode(node, selected))
+			s = append(s, t.String())
+		case filenode.FileNode:
+			s = append(s, applyStyleToNode(node, selected).Render(node.Value()))
+		}
+	}
+	return strings.Join(s, "\n")
 }
 
-func (m Model) printWithoutRoot() string {
-	if m.tree.Value() != dirIcon+"." {
-		return m.tree.String()
+// buildTree groups the files by directory, keeping their order, and merges
+// directories that only contain a single subdirectory.
+func buildTree(files []*gitdiff.File, collapsed map[string]bool) []tree.Node {
+	root := &dirnode.DirNode{}
+	for _, file := range files {
+		dir := root
+		parts := strings.Split(filenode.GetFileName(file), "/")
+		for i, part := range parts[:len(parts)-1] {
+			dir = childDir(dir, part, strings.Join(parts[:i+1], "/"))
+		}
+		dir.Items = append(dir.Items, filenode.FileNode{File: file})
 	}
 
-	s := ""
-	children := m.tree.Children()
-	for i := 0; i < children.Length(); i++ {
-		child := children.At(i)
-		switch child := child.(type) {
-		case *tree.Tree:
-			normalized := normalizeDepth(child, 0)
-			applyStyles(normalized, m.selectedFile)
+	for _, node := range root.Items {
+		if dir, ok := node.(*dirnode.DirNode); ok {
+			mergeSingleChildDirs(dir)
+		}
+	}
+	setDepth(root.Items, 0, collapsed)
+	return root.Items
+}
 
-			s += normalized.String()
-		case filenode.FileNode:
-			child.Depth = 0
-			s += applyStyleToNode(child, m.selectedFile).Render(child.Value())
+func childDir(dir *dirnode.DirNode, name string, path string) *dirnode.DirNode {
+	for _, item := range dir.Items {
+		if child, ok := item.(*dirnode.DirNode); ok && child.Name == name {
+			return child
+		}
+	}
+	child := &dirnode.DirNode{Name: name, Path: path}
+	dir.Items = append(dir.Items, child)
+	return child
+}
+
+func mergeSingleChildDirs(dir *dirnode.DirNode) {
+	for len(dir.Items) == 1 {
+		child, ok := dir.Items[0].(*dirnode.DirNode)
+		if !ok {
+			break
 		}
-		if i < children.Length()-1 {
-			s += "\n"
+		dir.Name = dir.Name + "/" + child.Name
+		dir.Path = child.Path
+		dir.Items = child.Items
+	}
+	for _, item := range dir.Items {
+		if child, ok := item.(*dirnode.DirNode); ok {
+			mergeSingleChildDirs(child)
 		}
 	}
-	return s
 }
 
-func normalizeDepth(node *tree.Tree, depth int) *tree.Tree {
-	t := tree.Root(node.Value())
-	children := node.Children()
-	for i := 0; i < children.Length(); i++ {
-		child := children.At(i)
-		switch child := child.(type) {
-		case *tree.Tree:
-			sub := normalizeDepth(child, depth+1)
-			t.Child(sub)
+func setDepth(nodes []tree.Node, depth int, collapsed map[string]bool) {
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *dirnode.DirNode:
+			node.Depth = depth
+			node.Collapsed = collapsed[node.Path]
+			setDepth(node.Items, depth+1, collapsed)
 		case filenode.FileNode:
-			child.Depth = depth + 1
-			t.Child(child)
+			node.Depth = depth
+			nodes[i] = node
 		}
 	}
-	return t
 }
 
-func buildFullFileTree(files []*gitdiff.File) *tree.Tree {
-	t := tree.Root(".")
-	for _, file := range files {
-		subTree := t
-
-		name := filenode.GetFileName(file)
-		dir := filepath.Dir(name)
-		parts := strings.Split(dir, string(os.PathSeparator))
-		path := ""
-
-		// walk the tree to find existing path
-		for _, part := range parts {
-			found := false
-			for j := 0; j < subTree.Children().Length(); j++ {
-				child := subTree.Children().At(j)
-				if child.Value() == part {
-					switch child := child.(type) {
-					case *tree.Tree:
-						subTree = child
-					}
-					path = path + part + string(os.PathSeparator)
-					found = true
-					break
-				}
+// annotate sets the viewed mark, comment count, generated mark and whitespace
+// only mark of the file nodes, any of the functions may be nil.
+func annotate(nodes []tree.Node, isViewed func(*gitdiff.File) bool, comments func(*gitdiff.File) int, generated, whitespaceOnly func(*gitdiff.File) bool) {
+	for i, node := range nodes {
+		switch node := node.(type) {
+		case *dirnode.DirNode:
+			annotate(node.Items, isViewed, comments, generated, whitespaceOnly)
+		case filenode.FileNode:
+			if isViewed != nil {
+				node.Viewed = isViewed(node.File)
 			}
-			if !found {
-				break
+			if comments != nil {
+				node.Comments = comments(node.File)
 			}
-		}
-
-		// path does not exist from this point, need to creat it
-		leftover := strings.TrimPrefix(name, path)
-		parts = strings.Split(leftover, string(os.PathSeparator))
-		for i, part := range parts {
-			var c *tree.Tree
-			if i == len(parts)-1 {
-				subTree.Child(filenode.FileNode{File: file})
-			} else {
-				c = tree.Root(part)
-				subTree.Child(c)
-				subTree = c
+			if generated != nil {
+				node.Generated = generated(node.File)
 			}
+			if whitespaceOnly != nil {
+				node.WhitespaceOnly = whitespaceOnly(node.File)
+			}
+			nodes[i] = node
 		}
 	}
-
-	return t
 }
 
-func collapseTree(t *tree.Tree) *tree.Tree {
-	children := t.Children()
-	newT := tree.Root(t.Value())
-	if children.Length() == 0 {
-		return newT
-	}
-
-	for i := 0; i < children.Length(); i++ {
-		child := t.Children().At(i)
-		switch child := child.(type) {
-		case *tree.Tree:
-			collapsedChild := collapseTree(child)
-			newT.Child(collapsedChild)
-		default:
-			newT.Child(child)
+// flatten lists the nodes in display order, skipping the content of collapsed
+// directories unless all is set.
+func flatten(nodes []tree.Node, all bool) []tree.Node {
+	rows := make([]tree.Node, 0, len(nodes))
+	for _, node := range nodes {
+		rows = append(rows, node)
+		if dir, ok := node.(*dirnode.DirNode); ok && (all || !dir.Collapsed) {
+			rows = append(rows, flatten(dir.Items, all)...)
 		}
 	}
+	return rows
+}
 
-	newChildren := newT.Children()
-	if newChildren.Length() == 1 {
-		child := newChildren.At(0)
-		switch child := child.(type) {
-		case *tree.Tree:
-			if t.Value() == "." {
-				return child
+// ancestors returns the directories containing file, outermost first.
+func ancestors(nodes []tree.Node, file *gitdiff.File) []*dirnode.DirNode {
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *dirnode.DirNode:
+			if found := ancestors(node.Items, file); found != nil {
+				return append([]*dirnode.DirNode{node}, found...)
+			}
+		case filenode.FileNode:
+			if node.File == file {
+				return []*dirnode.DirNode{}
 			}
-
-			val := t.Value() + string(os.PathSeparator) + child.Value()
-			collapsed := tree.Root(val).Child(child.Children())
-			return collapsed
 		}
 	}
-
-	return newT
+	return nil
 }
 
-const dirIcon = " "
-
-func truncateTree(t *tree.Tree, depth int, numNodes int, numChildren int) (*tree.Tree, int) {
-	newT := tree.Root(utils.TruncateString(dirIcon+t.Value(), constants.OpenFileTreeWidth-depth*2))
-	numNodes++
-	children := t.Children()
-	for i := 0; i < children.Length(); i++ {
-		child := children.At(i)
-		numChildren++
-		switch child := child.(type) {
-		case *tree.Tree:
-			sub, subNum := truncateTree(child, depth+1, numNodes, 0)
-			numChildren += subNum
-			numNodes += subNum + 1
-			newT.Child(sub)
-		case filenode.FileNode:
-			numNodes++
-			newT.Child(filenode.FileNode{File: child.File, Depth: depth + 1, YOffset: numNodes})
-		default:
-			newT.Child(child)
-		}
+func sameNode(a, b tree.Node) bool {
+	switch a := a.(type) {
+	case *dirnode.DirNode:
+		b, ok := b.(*dirnode.DirNode)
+		return ok && a.Path == b.Path
+	case filenode.FileNode:
+		b, ok := b.(filenode.FileNode)
+		return ok && a.File == b.File
 	}
-	return newT, numChildren
+	return false
 }
 
-func applyStyles(t *tree.Tree, selectedFile *string) {
-	enumeratorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8")).PaddingRight(1)
-	rootStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+func applyStyles(t *tree.Tree, selected tree.Node) {
+	enumeratorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(config.Get().Theme.Border)).PaddingRight(1)
 	t.Enumerator(enumerator).Indenter(indenter).
 		EnumeratorStyle(enumeratorStyle).
-		ItemStyleFunc(applyStyle(selectedFile)).RootStyle(rootStyle)
+		ItemStyleFunc(applyStyle(selected))
 }
 
-func applyStyle(selectedFile *string) tree.StyleFunc {
+func applyStyle(selected tree.Node) tree.StyleFunc {
 	return func(children tree.Children, i int) lipgloss.Style {
-		return applyStyleAux(children, i, selectedFile)
+		return applyStyleAux(children, i, selected)
 	}
 }
 
-func applyStyleAux(children tree.Children, i int, selectedFile *string) lipgloss.Style {
-	st := lipgloss.NewStyle().Background(lipgloss.Color("2"))
+func applyStyleAux(children tree.Children, i int, selected tree.Node) lipgloss.Style {
+	st := lipgloss.NewStyle()
 	if children.Length() == 0 {
 		return st
 	}
 	child := children.At(i)
-	return applyStyleToNode(child, selectedFile)
+	return applyStyleToNode(child, selected)
 }
 
-func applyStyleToNode(node tree.Node, selectedFile *string) lipgloss.Style {
+func applyStyleToNode(node tree.Node, selected tree.Node) lipgloss.Style {
+	theme := config.Get().Theme
 	st := lipgloss.NewStyle().MaxHeight(1)
-	switch n := node.(type) {
-	case filenode.FileNode:
-		if selectedFile != nil && n.Path() == *selectedFile {
-			return st.Background(lipgloss.Color("#1b1b33")).Bold(true)
-		}
-	case *tree.Tree:
-		return st.Foreground(lipgloss.Color("4"))
+	if selected != nil && sameNode(node, selected) {
+		st = st.Background(lipgloss.Color(theme.SelectedBackground)).Bold(true)
+	}
+	switch node.(type) {
+	case *dirnode.DirNode:
+		return st.Foreground(lipgloss.Color(theme.Directory))
 	default:
 		return st
 	}
-	return st
 }
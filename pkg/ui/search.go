@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/fuzzy"
+)
+
+// searchResult is a file matching the search, positions are the indices of
+// the matched runes in its name.
+type searchResult struct {
+	file      *gitdiff.File
+	name      string
+	positions []int
+}
+
+// filterResults ranks the files against the search, all of them are listed
+// in tree order while it's empty.
+func (m *mainModel) filterResults() {
+	names := make([]string, len(m.shown))
+	for i, f := range m.shown {
+		names[i] = filenode.GetFileName(f)
+	}
+	matches := fuzzy.Find(m.search.Value(), names)
+	m.filtered = make([]searchResult, 0, len(matches))
+	for _, match := range matches {
+		m.filtered = append(m.filtered, searchResult{
+			file:      m.shown[match.Index],
+			name:      names[match.Index],
+			positions: match.Positions,
+		})
+	}
+	m.resultsCursor = max(0, min(m.resultsCursor, len(m.filtered)-1))
+	m.resultsVp.SetContent(m.resultsView())
+}
+
+// scrollResults keeps the result under the cursor in view.
+func (m *mainModel) scrollResults() {
+	m.resultsVp.SetContent(m.resultsView())
+	if m.resultsCursor < m.resultsVp.YOffset {
+		m.resultsVp.SetYOffset(m.resultsCursor)
+	} else if m.resultsCursor >= m.resultsVp.YOffset+m.resultsVp.Height {
+		m.resultsVp.SetYOffset(m.resultsCursor - m.resultsVp.Height + 1)
+	}
+}
+
+// previewResult shows the diff of the result under the cursor.
+func (m *mainModel) previewResult() tea.Cmd {
+	if m.resultsCursor >= len(m.filtered) {
+		return nil
+	}
+	return m.preview(m.filtered[m.resultsCursor].file)
+}
+
+// preview shows the diff of file without selecting it in the tree.
+func (m *mainModel) preview(file *gitdiff.File) tea.Cmd {
+	if file == m.previewed || (m.previewed == nil && file == m.current) {
+		return nil
+	}
+	m.previewed = file
+	var cmd tea.Cmd
+	m.diffViewer, cmd = m.diffViewer.SetFilePatch(file)
+	return cmd
+}
+
+// cancelPreview goes back to the diff of the selected file.
+func (m *mainModel) cancelPreview() tea.Cmd {
+	if m.previewed == nil {
+		return nil
+	}
+	m.previewed = nil
+	var cmd tea.Cmd
+	m.diffViewer, cmd = m.diffViewer.SetFilePatch(m.current)
+	return cmd
+}
+
+// selectResult selects file in the tree, keeping its preview as is.
+func (m *mainModel) selectResult(file *gitdiff.File) tea.Cmd {
+	if m.previewed != file {
+		m.cancelPreview()
+		return m.selectFile(file)
+	}
+	m.previewed = nil
+	m.fileTree = m.fileTree.SelectFile(file)
+	m.current = file
+	return m.prefetchNeighbors()
+}
+
+// highlightMatches renders name with the runes at positions in the matched style.
+func highlightMatches(name string, positions []int, base, matched lipgloss.Style) string {
+	var sb strings.Builder
+	var run strings.Builder
+	inMatch, p := false, 0
+	flush := func() {
+		if inMatch {
+			sb.WriteString(matched.Render(run.String()))
+		} else {
+			sb.WriteString(base.Render(run.String()))
+		}
+		run.Reset()
+	}
+	for i, r := range []rune(name) {
+		isMatch := p < len(positions) && positions[p] == i
+		if isMatch {
+			p++
+		}
+		if isMatch != inMatch && run.Len() > 0 {
+			flush()
+		}
+		inMatch = isMatch
+		run.WriteRune(r)
+	}
+	flush()
+	return sb.String()
+}
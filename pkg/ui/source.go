@@ -0,0 +1,210 @@
+package ui
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// flushInterval is how often newly parsed files are sent to the UI.
+const flushInterval = 100 * time.Millisecond
+
+var (
+	// prettyStart is the first line of a commit in the output of git log -p
+	// and git show.
+	prettyStart = regexp.MustCompile(`^commit [0-9a-f]{7,}\b`)
+	// mailStart is the first line of a commit in the output of git
+	// format-patch, which always has this date.
+	mailStart = regexp.MustCompile(`^From [0-9a-f]{40} Mon Sep 17 00:00:00 2001$`)
+)
+
+// Source streams the files diffnav displays, calling emit with each batch of
+// files as soon as they're available. When the input is a series of commits,
+// e.g. git log -p, header is the commit the files belong to and it's emitted
+// once without files when the commit starts. It's nil for a plain diff.
+type Source func(emit func(header *gitdiff.PatchHeader, files []*gitdiff.File)) error
+
+// PatchSource parses a patch, e.g. the output of git diff, while it's being read.
+// The input is cut at every "diff --git" header so each file is parsed on its own,
+// without holding the whole patch in memory, and at every commit header. The
+// parsed files are sent on a timer, so that they show up even while the input
+// stalls.
+func PatchSource(r io.Reader) Source {
+	return func(emit func(header *gitdiff.PatchHeader, files []*gitdiff.File)) error {
+		reader := bufio.NewReader(r)
+		var chunk, preamble strings.Builder
+		hasHeader := false
+		// inPreamble is set from the start of a commit to its first file.
+		inPreamble := false
+		// mu guards the header and batch, which are flushed by the ticker
+		var mu sync.Mutex
+		var header *gitdiff.PatchHeader
+		batch := make([]*gitdiff.File, 0)
+
+		flush := func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if len(batch) > 0 {
+				emit(header, batch)
+				batch = make([]*gitdiff.File, 0)
+			}
+		}
+
+		ticker := time.NewTicker(flushInterval)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ticker.C:
+					flush()
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			ticker.Stop()
+			close(done)
+			wg.Wait()
+		}()
+
+		parseChunk := func() error {
+			files, _, err := gitdiff.Parse(strings.NewReader(chunk.String()))
+			chunk.Reset()
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			batch = append(batch, files...)
+			mu.Unlock()
+			return nil
+		}
+
+		startCommit := func() {
+			inPreamble = false
+			mu.Lock()
+			defer mu.Unlock()
+			header = parseHeader(preamble.String())
+			preamble.Reset()
+			emit(header, nil)
+		}
+
+		// endCommit sends the last files of the commit, batches never mix commits.
+		endCommit := func() error {
+			if inPreamble {
+				// a commit without files, e.g. a merge
+				startCommit()
+			}
+			if err := parseChunk(); err != nil {
+				return err
+			}
+			hasHeader = false
+			flush()
+			return nil
+		}
+
+		read := func() (string, error) {
+			line, err := reader.ReadString('\n')
+			line = ansi.Strip(line)
+			// go-gitdiff only recognizes the marker of binary files without
+			// their content when it has no file names, which git always prints
+			if strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ\n") {
+				line = "Binary files differ\n"
+			}
+			return line, err
+		}
+
+		// the next line is read ahead to recognize the start of commits
+		prev := ""
+		line, err := read()
+		for {
+			if err != nil && err != io.EOF {
+				return err
+			}
+			next, nextErr := "", io.EOF
+			if err == nil {
+				next, nextErr = read()
+			}
+			if startsCommit(prev, line, next) {
+				if err := endCommit(); err != nil {
+					return err
+				}
+				inPreamble = true
+			}
+			if strings.HasPrefix(line, "diff --git ") {
+				if inPreamble {
+					startCommit()
+				} else if hasHeader {
+					if err := parseChunk(); err != nil {
+						return err
+					}
+				}
+				hasHeader = true
+			}
+			if inPreamble {
+				preamble.WriteString(line)
+			} else {
+				chunk.WriteString(line)
+			}
+			if err == io.EOF {
+				break
+			}
+			prev = line
+			line, err = next, nextErr
+		}
+
+		if inPreamble {
+			startCommit()
+		}
+		chunk.WriteString("\n")
+		if err := parseChunk(); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	}
+}
+
+// startsCommit reports whether line is the first line of a commit, which is
+// the first line of the input or follows a blank line. It's followed by the
+// author, or the parents of a merge in the output of git log, so that a
+// message quoting a commit isn't mistaken for one.
+func startsCommit(prev, line, next string) bool {
+	if strings.TrimSpace(prev) != "" {
+		return false
+	}
+	if mailStart.MatchString(strings.TrimSuffix(line, "\n")) {
+		return strings.HasPrefix(next, "From: ")
+	}
+	return prettyStart.MatchString(line) && (strings.HasPrefix(next, "Author:") || strings.HasPrefix(next, "Merge:"))
+}
+
+// parseHeader reads the author, dates and message of a commit. A header that
+// doesn't parse is kept with its first line as the title.
+func parseHeader(text string) *gitdiff.PatchHeader {
+	header, err := gitdiff.ParsePatchHeader(text)
+	if err != nil {
+		log.Debug("failed parsing commit header", "err", err)
+		title, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+		return &gitdiff.PatchHeader{Title: title}
+	}
+	return header
+}
+
+// FilesSource displays files that were already diffed.
+func FilesSource(files []*gitdiff.File) Source {
+	return func(emit func(header *gitdiff.PatchHeader, files []*gitdiff.File)) error {
+		emit(nil, files)
+		return nil
+	}
+}
@@ -0,0 +1,145 @@
+// Package viewed remembers which files were marked as reviewed, across runs.
+//
+// A file stays viewed as long as its patch doesn't change, so reopening a diff
+// after new changes only resets the files that were touched again.
+package viewed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+	"github.com/charmbracelet/log"
+
+	"github.com/dlvhdr/diffnav/pkg/filenode"
+	"github.com/dlvhdr/diffnav/pkg/utils"
+)
+
+const stateFile = "viewed.json"
+
+// Store holds the viewed files of a single repository.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	repo string
+	// files maps a file's path to the hash of its patch when it was viewed.
+	files map[string]string
+	// hashes caches the patch hash of each file.
+	hashes map[*gitdiff.File]string
+}
+
+// state is the content of the state file, the viewed files of every repository.
+type state map[string]map[string]string
+
+// Load reads the viewed files of repo from the state file. A missing or
+// corrupt state file is not an error.
+func Load(repo string) (*Store, error) {
+	dir, err := utils.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: filepath.Join(dir, stateFile), repo: repo, hashes: make(map[*gitdiff.File]string)}
+	st, err := readState(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.files = st[repo]
+	if s.files == nil {
+		s.files = make(map[string]string)
+	}
+	return s, nil
+}
+
+// IsViewed reports whether file was viewed and hasn't changed since.
+func (s *Store) IsViewed(file *gitdiff.File) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hash, ok := s.files[filenode.GetFileName(file)]
+	return ok && hash == s.hash(file)
+}
+
+// SetViewed marks file as viewed or not, call Save to persist it.
+func (s *Store) SetViewed(file *gitdiff.File, viewed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if viewed {
+		s.files[filenode.GetFileName(file)] = s.hash(file)
+	} else {
+		delete(s.files, filenode.GetFileName(file))
+	}
+}
+
+// Save writes the repository's viewed files to the state file, keeping the
+// other repositories' as they are on disk.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, err := readState(s.path)
+	if err != nil {
+		return err
+	}
+	if len(s.files) == 0 {
+		delete(st, s.repo)
+	} else {
+		st[s.repo] = s.files
+	}
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(s.path, data)
+}
+
+func (s *Store) hash(file *gitdiff.File) string {
+	hash, ok := s.hashes[file]
+	if !ok {
+		hash = PatchHash(file)
+		s.hashes[file] = hash
+	}
+	return hash
+}
+
+// PatchHash identifies the changes made to a file. Only the names and the
+// changed lines are part of it, so it's stable across rebases that don't touch
+// the file.
+func PatchHash(file *gitdiff.File) string {
+	h := sha256.New()
+	h.Write([]byte(file.OldName + "\x00" + file.NewName + "\x00"))
+	for _, frag := range file.TextFragments {
+		for _, line := range frag.Lines {
+			if line.Op != gitdiff.OpContext {
+				h.Write([]byte(line.String()))
+			}
+		}
+		h.Write([]byte{0})
+	}
+	for _, frag := range []*gitdiff.BinaryFragment{file.BinaryFragment, file.ReverseBinaryFragment} {
+		if frag != nil {
+			h.Write(frag.Data)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func readState(path string) (state, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return make(state), nil
+	} else if err != nil {
+		return nil, err
+	}
+	st := make(state)
+	if err := json.Unmarshal(data, &st); err != nil {
+		// the state is only a cache, start over rather than failing
+		log.Debug("ignoring corrupt viewed files", "path", path, "err", err)
+		return make(state), nil
+	}
+	return st, nil
+}
@@ -0,0 +1,122 @@
+package viewed
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+func parseFile(t *testing.T, name, added string) *gitdiff.File {
+	t.Helper()
+	patch := "diff --git a/" + name + " b/" + name + `
+index 1111111..2222222 100644
+--- a/` + name + `
++++ b/` + name + `
+@@ -1,2 +1,2 @@
+ keep
+-old
++` + added + "\n"
+	files, _, err := gitdiff.Parse(strings.NewReader(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files[0]
+}
+
+func TestSaveLoad(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	a := parseFile(t, "a.go", "new")
+	b := parseFile(t, "b.go", "new")
+
+	store, err := Load("/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetViewed(a, true)
+	store.SetViewed(b, true)
+	store.SetViewed(b, false)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	other, err := Load("/other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.SetViewed(b, true)
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		repo string
+		file *gitdiff.File
+		want bool
+	}{
+		{repo: "/repo", file: a, want: true},
+		{repo: "/repo", file: b, want: false},
+		// the same file in another repository is unrelated
+		{repo: "/other", file: a, want: false},
+		{repo: "/other", file: b, want: true},
+		// the same patch is viewed when the diff is parsed again
+		{repo: "/repo", file: parseFile(t, "a.go", "new"), want: true},
+		// a changed patch un-marks the file
+		{repo: "/repo", file: parseFile(t, "a.go", "newer"), want: false},
+	}
+	for _, tt := range tests {
+		store, err := Load(tt.repo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := store.IsViewed(tt.file); got != tt.want {
+			t.Errorf("%s: IsViewed(%s) = %v, want %v", tt.repo, tt.file.NewName, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	path := filepath.Join(dir, "diffnav", stateFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := Load("/repo")
+	if err != nil {
+		t.Fatalf("Load() = %v, want a corrupt state to be ignored", err)
+	}
+	file := parseFile(t, "a.go", "new")
+	if store.IsViewed(file) {
+		t.Errorf("IsViewed() = true, want false")
+	}
+	store.SetViewed(file, true)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if store, err = Load("/repo"); err != nil || !store.IsViewed(file) {
+		t.Errorf("Load() after saving over a corrupt state = %v, viewed %v", err, err == nil && store.IsViewed(file))
+	}
+}
+
+func TestPatchHash(t *testing.T) {
+	base := PatchHash(parseFile(t, "a.go", "new"))
+	tests := []struct {
+		name string
+		file *gitdiff.File
+		same bool
+	}{
+		{name: "same patch", file: parseFile(t, "a.go", "new"), same: true},
+		{name: "changed line", file: parseFile(t, "a.go", "newer"), same: false},
+		{name: "other file", file: parseFile(t, "b.go", "new"), same: false},
+	}
+	for _, tt := range tests {
+		if got := PatchHash(tt.file) == base; got != tt.same {
+			t.Errorf("%s: same hash = %v, want %v", tt.name, got, tt.same)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Package worddiff finds the words that changed between a removed line and the
+// added line that replaced it.
+package worddiff
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+const (
+	// similarityThreshold is the minimal share of unchanged text for two lines
+	// to be considered versions of each other rather than unrelated lines.
+	similarityThreshold = 0.4
+	// maxTokens bounds the quadratic LCS pass on pathologically long lines.
+	maxTokens = 1000
+	// maxRunLines bounds the number of lines of a run compared with each
+	// other, longer runs are paired line by line.
+	maxRunLines = 64
+)
+
+// Range is a byte range [Start, End) within a line.
+type Range struct {
+	Start int
+	End   int
+}
+
+// Pair is a removed line matched with the added line that replaced it, both are
+// indices into the lines of a fragment.
+type Pair struct {
+	Old int
+	New int
+	// OldRanges and NewRanges are the changed parts of each line.
+	OldRanges []Range
+	NewRanges []Range
+}
+
+// PairLines walks each run of deleted lines followed by added lines and pairs
+// every deleted line with the most similar added line after the previous match.
+// In runs longer than maxRunLines, the n-th deleted line is only compared with
+// the n-th added line.
+//
+// text returns the content of a line as it should be compared, which lets
+// callers diff the same text they display (e.g. with tabs expanded).
+func PairLines(lines []gitdiff.Line, text func(gitdiff.Line) string) []Pair {
+	pairs := make([]Pair, 0)
+	for i := 0; i < len(lines); {
+		if lines[i].Op != gitdiff.OpDelete {
+			i++
+			continue
+		}
+
+		deleted := make([]int, 0)
+		for ; i < len(lines) && lines[i].Op == gitdiff.OpDelete; i++ {
+			deleted = append(deleted, i)
+		}
+		added := make([]int, 0)
+		for ; i < len(lines) && lines[i].Op == gitdiff.OpAdd; i++ {
+			added = append(added, i)
+		}
+
+		if len(deleted) > maxRunLines || len(added) > maxRunLines {
+			for k := 0; k < min(len(deleted), len(added)); k++ {
+				old, new, score := diff(text(lines[deleted[k]]), text(lines[added[k]]))
+				if score >= similarityThreshold {
+					pairs = append(pairs, Pair{Old: deleted[k], New: added[k], OldRanges: old, NewRanges: new})
+				}
+			}
+			continue
+		}
+
+		next := 0
+		for _, d := range deleted {
+			best, bestScore := -1, 0.0
+			var bestOld, bestNew []Range
+			for j := next; j < len(added); j++ {
+				old, new, score := diff(text(lines[d]), text(lines[added[j]]))
+				if score > bestScore {
+					best, bestScore, bestOld, bestNew = j, score, old, new
+				}
+				if score == 1 {
+					break
+				}
+			}
+			if best == -1 || bestScore < similarityThreshold {
+				continue
+			}
+			pairs = append(pairs, Pair{Old: d, New: added[best], OldRanges: bestOld, NewRanges: bestNew})
+			next = best + 1
+		}
+	}
+	return pairs
+}
+
+// Diff returns the byte ranges of a and b that aren't part of their longest
+// common sequence of words.
+func Diff(a, b string) (old []Range, new []Range) {
+	old, new, _ = diff(a, b)
+	return old, new
+}
+
+func diff(a, b string) ([]Range, []Range, float64) {
+	if a == b {
+		return nil, nil, 1
+	}
+	ta, tb := tokenize(a), tokenize(b)
+	if len(ta) > maxTokens || len(tb) > maxTokens {
+		return []Range{{0, len(a)}}, []Range{{0, len(b)}}, 0
+	}
+
+	keepA, keepB := lcs(a, b, ta, tb)
+	common := 0
+	for i, keep := range keepA {
+		if keep {
+			common += ta[i].End - ta[i].Start
+		}
+	}
+	score := 0.0
+	if len(a)+len(b) > 0 {
+		score = float64(2*common) / float64(len(a)+len(b))
+	}
+	return changedRanges(ta, keepA), changedRanges(tb, keepB), score
+}
+
+// lcs marks which tokens of each side belong to their longest common subsequence.
+func lcs(a, b string, ta, tb []Range) ([]bool, []bool) {
+	n, m := len(ta), len(tb)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[ta[i].Start:ta[i].End] == b[tb[j].Start:tb[j].End] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+
+	keepA, keepB := make([]bool, n), make([]bool, m)
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[ta[i].Start:ta[i].End] == b[tb[j].Start:tb[j].End]:
+			keepA[i], keepB[j] = true, true
+			i++
+			j++
+		case table[i+1][j] >= table[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return keepA, keepB
+}
+
+// changedRanges merges consecutive tokens that aren't kept into ranges.
+func changedRanges(tokens []Range, keep []bool) []Range {
+	ranges := make([]Range, 0)
+	for i, token := range tokens {
+		if keep[i] {
+			continue
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End == token.Start {
+			ranges[n-1].End = token.End
+			continue
+		}
+		ranges = append(ranges, token)
+	}
+	return ranges
+}
+
+// tokenize splits s into words, runs of whitespace and single punctuation characters.
+func tokenize(s string) []Range {
+	tokens := make([]Range, 0)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		class := classOf(r)
+		end := i + size
+		if class != punctClass {
+			for end < len(s) {
+				next, nextSize := utf8.DecodeRuneInString(s[end:])
+				if classOf(next) != class {
+					break
+				}
+				end += nextSize
+			}
+		}
+		tokens = append(tokens, Range{Start: i, End: end})
+		i = end
+	}
+	return tokens
+}
+
+const (
+	wordClass = iota
+	spaceClass
+	punctClass
+)
+
+func classOf(r rune) int {
+	switch {
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return wordClass
+	case unicode.IsSpace(r):
+		return spaceClass
+	}
+	return punctClass
+}
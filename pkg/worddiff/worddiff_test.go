@@ -0,0 +1,109 @@
+package worddiff
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bluekeyes/go-gitdiff/gitdiff"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		old, new []Range
+	}{
+		{name: "equal", a: "foo bar", b: "foo bar"},
+		{name: "changed word", a: "foo bar baz", b: "foo qux baz", old: []Range{{4, 7}}, new: []Range{{4, 7}}},
+		{name: "added word", a: "foo baz", b: "foo bar baz", old: []Range{}, new: []Range{{4, 8}}},
+		{name: "punctuation", a: "f(a)", b: "f(a, b)", old: []Range{}, new: []Range{{3, 6}}},
+		{name: "unicode", a: "héllo wörld", b: "héllo world", old: []Range{{7, 13}}, new: []Range{{7, 12}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := Diff(tt.a, tt.b)
+			if !reflect.DeepEqual(old, tt.old) || !reflect.DeepEqual(new, tt.new) {
+				t.Errorf("Diff(%q, %q) = %v, %v, want %v, %v", tt.a, tt.b, old, new, tt.old, tt.new)
+			}
+		})
+	}
+}
+
+func TestPairLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []gitdiff.Line
+		want  [][2]int
+	}{
+		{
+			name:  "single replacement",
+			lines: []gitdiff.Line{del("x := 1"), add("x := 2")},
+			want:  [][2]int{{0, 1}},
+		},
+		{
+			name:  "unrelated lines",
+			lines: []gitdiff.Line{del("return nil"), add("// a completely different comment")},
+			want:  [][2]int{},
+		},
+		{
+			name: "most similar line",
+			lines: []gitdiff.Line{
+				del("name := file.NewName"),
+				add("// the new name"),
+				add("name := file.OldName"),
+			},
+			want: [][2]int{{0, 2}},
+		},
+		{
+			name: "in order",
+			lines: []gitdiff.Line{
+				del("first line here"), del("second line here"),
+				ctx("unchanged"),
+				del("third line here"), add("third line there"),
+			},
+			want: [][2]int{{3, 4}},
+		},
+		{
+			name:  "long run by index",
+			lines: longRun(maxRunLines + 1),
+			want:  indexPairs(maxRunLines + 1),
+		},
+	}
+	text := func(line gitdiff.Line) string { return line.Line }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([][2]int, 0)
+			for _, pair := range PairLines(tt.lines, text) {
+				got = append(got, [2]int{pair.Old, pair.New})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PairLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func del(text string) gitdiff.Line { return gitdiff.Line{Op: gitdiff.OpDelete, Line: text} }
+func add(text string) gitdiff.Line { return gitdiff.Line{Op: gitdiff.OpAdd, Line: text} }
+func ctx(text string) gitdiff.Line { return gitdiff.Line{Op: gitdiff.OpContext, Line: text} }
+
+// longRun replaces n numbered lines, each with a similar line.
+func longRun(n int) []gitdiff.Line {
+	lines := make([]gitdiff.Line, 0, 2*n)
+	for i := 0; i < n; i++ {
+		lines = append(lines, del(fmt.Sprintf("value %d of the table", i)))
+	}
+	for i := 0; i < n; i++ {
+		lines = append(lines, add(fmt.Sprintf("value %d of the table;", i)))
+	}
+	return lines
+}
+
+func indexPairs(n int) [][2]int {
+	pairs := make([][2]int, n)
+	for i := range pairs {
+		pairs[i] = [2]int{i, n + i}
+	}
+	return pairs
+}